@@ -22,9 +22,12 @@ package config
 
 import (
 	"flag"
+	"fmt"
 	"os"
 	"runtime"
+	"time"
 
+	"github.com/corsc/go-tools/package-coverage/notifier"
 	"github.com/corsc/go-tools/package-coverage/utils"
 )
 
@@ -51,26 +54,58 @@ type Config struct {
 	// DoClean will "clean up" by removing any calculated coverage files
 	DoClean bool
 
-	// DoPrint will output the result to StdOut
+	// Recover will remove the files left behind by an aborted run (and do nothing else)
+	Recover bool
+
+	// DoPrint will output the result to StdOut (StdErr when MachineOutput is set)
 	DoPrint bool
 
+	// MachineOutput is set when a machine-readable output is written to StdOut (the console output is then written to
+	// StdErr so that StdOut remains parsable)
+	MachineOutput bool
+
+	// PrintFiles will add the coverage of each file to the output to StdOut
+	PrintFiles bool
+
+	// PrintFuncs will add the coverage of each function to the output to StdOut
+	PrintFuncs bool
+
 	// IgnorePaths allows you to ignore file paths matching the specified regex
 	// (match directories by surrounding the directory name with slashes; match files by prefixing with a slash)
 	IgnorePaths string
 
-	// WebHook is the Slack WebHook URL (missing means don't send)
+	// WebHook is the URL the coverage is sent to by the Notifier (missing means don't send)
 	WebHook string
 
+	// Notifier is the format the coverage is sent to the WebHook in (slack, teams or template)
+	Notifier string
+
+	// WebHookTemplate is the file containing the Go template of the JSON body (required by the template Notifier)
+	WebHookTemplate string
+
+	// WebHookTimeout is the timeout of each request to the WebHook
+	WebHookTimeout time.Duration
+
+	// WebHookRetries is the number of times a failed request to the WebHook is retried
+	WebHookRetries int
+
 	// ChannelOverride allows you to override the WebHook's default Slack channel
 	ChannelOverride string
 
+	// GoodCoverage is the coverage above which a package is shown as good by the Notifier and the badges
+	GoodCoverage float64
+
+	// WarningCoverage is the coverage above which a package is shown as a warning (and otherwise as danger) by the Notifier and the badges
+	WarningCoverage float64
+
 	// Prefix is the directory structure to be removed from all package names (makes the output cleaner)
 	Prefix string
 
 	// Depth is how many levels of coverage to output (default is 0 = all)
 	Depth int
 
-	// MinCoverage causes output to StdOut to be colored red for any package below this amount of coverage
+	// MinCoverage causes output to StdOut to be colored red (and the run to fail) for any package below this amount of
+	// coverage
 	MinCoverage int
 
 	// Tags is the go build tags to be added in go test calls
@@ -78,6 +113,99 @@ type Config struct {
 
 	// Race is used to enable --race flag
 	Race bool
+
+	// CoverMode is the go test -covermode (set, count or atomic; missing means the go test default)
+	CoverMode string
+
+	// Uncovered lists the uncovered code of all packages (all) or of the packages below MinCoverage (below); missing means don't list
+	Uncovered string
+
+	// UncoveredContext is how many lines of source to output before and after each uncovered range
+	UncoveredContext int
+
+	// HotPaths is how many of the most and least executed blocks and functions to output for each package (requires count or atomic CoverMode; 0 = none)
+	HotPaths int
+
+	// CoverPkg is the pattern of packages (e.g. ./...) the tests of each directory calculate coverage for (missing means the tested package only)
+	CoverPkg string
+
+	// Timeout is the maximum duration of the tests of each directory (passed to go test; go test is killed when it does not stop; 0 means go test's own default)
+	Timeout time.Duration
+
+	// Slowest is how many of the slowest packages (by wall-clock duration) to output with the test results (0 = none)
+	Slowest int
+
+	// CacheDir is the directory the coverage of directories whose sources (and dependencies) are unchanged is reused from (missing means don't cache)
+	CacheDir string
+
+	// Shard is which of the shards of the directories to test as i/n (e.g. 2/4; missing means test all directories)
+	Shard string
+
+	// ShardIndex is the (1 based) index of the shard (parsed from Shard)
+	ShardIndex int
+
+	// ShardCount is the number of shards (parsed from Shard; 0 means no sharding)
+	ShardCount int
+
+	// TimingsFile contains the durations of a previous run used to balance the shards (missing means split evenly)
+	TimingsFile string
+
+	// TimingsSave is the file the durations of testing each directory are saved to (missing means don't save)
+	TimingsSave string
+
+	// ShardOutput is the directory the coverage, test results and timings of the shard are saved to (missing means don't save)
+	ShardOutput string
+
+	// ShardMerge is a comma separated list of shard artifact directories (glob patterns) to report on instead of calculating the coverage
+	ShardMerge string
+
+	// MergeProfiles is a comma separated list of additional coverage profiles (glob patterns) to merge into all outputs
+	MergeProfiles string
+
+	// PrintAttribution will add which tests contributed the coverage of each package to the output to StdOut
+	PrintAttribution bool
+
+	// JSONOutput is the file the per-package coverage should be written to as JSON ("-" means StdOut; missing means don't write)
+	JSONOutput string
+
+	// CoberturaOutput is the file the coverage should be written to as Cobertura XML ("-" means StdOut; missing means don't write)
+	CoberturaOutput string
+
+	// LCOVOutput is the file the coverage should be written to as an LCOV tracefile ("-" means StdOut; missing means don't write)
+	LCOVOutput string
+
+	// JUnitOutput is the file the results of the tests should be written to as JUnit XML ("-" means StdOut; missing means don't write)
+	JUnitOutput string
+
+	// MarkdownOutput is the file a Markdown summary of the coverage should be written to ("-" means StdOut; missing means don't write)
+	MarkdownOutput string
+
+	// MarkdownBaseline is a coverage profile the Markdown summary is compared to (missing means don't compare)
+	MarkdownBaseline string
+
+	// BadgeOutput is the directory SVG coverage badges should be written to (missing means don't write)
+	BadgeOutput string
+
+	// TreemapOutput is the file the coverage should be written to as an SVG treemap ("-" means StdOut; missing means don't write)
+	TreemapOutput string
+
+	// HTMLOutput is the directory a static HTML report should be written to (missing means don't write)
+	HTMLOutput string
+
+	// Baseline is a previously saved baseline file to compare the coverage against (missing means don't compare)
+	Baseline string
+
+	// BaselineSave is the file the current per-package coverage should be saved to as a baseline (missing means don't save)
+	BaselineSave string
+
+	// BaselineTolerance is how many percent a package's coverage can drop before it is considered a regression
+	BaselineTolerance float64
+
+	// DiffBase is the git ref the changed lines are calculated from (missing means don't calculate diff coverage)
+	DiffBase string
+
+	// DiffMinCoverage is the minimum coverage of the changed statements
+	DiffMinCoverage int
 }
 
 // GetConfig will extra config from flags and return
@@ -91,15 +219,53 @@ func GetConfig() *Config {
 	flag.BoolVar(&(cfg.Coverage), "c", false, "generate coverage")
 	flag.BoolVar(&(cfg.SingleDir), "s", false, "only generate for the supplied directory (no recursion / will ignore -i)")
 	flag.BoolVar(&(cfg.DoClean), "d", false, "clean")
-	flag.BoolVar(&(cfg.DoPrint), "p", false, "print coverage to stdout")
+	flag.BoolVar(&(cfg.Recover), "recover", false, "remove the files left behind by an aborted run (and do nothing else)")
+	flag.BoolVar(&(cfg.DoPrint), "p", false, "print coverage to stdout (stderr when another output is written to stdout)")
+	flag.BoolVar(&(cfg.PrintFiles), "files", false, "also print the coverage of each file (grouped by package)")
+	flag.BoolVar(&(cfg.PrintFuncs), "func", false, "also print the coverage of each function")
 	flag.StringVar(&(cfg.IgnorePaths), "i", `./\.git.*|./_.*`, "ignore file paths matching the specified regex (match directories by surrounding the directory name with slashes; match files by prefixing with a slash)")
-	flag.StringVar(&(cfg.WebHook), "webhook", "", "Slack webhook URL (missing means don't send)")
+	flag.StringVar(&(cfg.WebHook), "webhook", "", "webhook URL the coverage is sent to (missing means don't send)")
+	flag.StringVar(&(cfg.Notifier), "notifier", "slack", "format used to send the coverage to the webhook: slack, teams or template")
+	flag.StringVar(&(cfg.WebHookTemplate), "webhook-template", "", "file containing the Go template of the JSON body sent to the webhook (used with -notifier=template)")
+	flag.DurationVar(&(cfg.WebHookTimeout), "webhook-timeout", notifier.DefaultTimeout, "timeout of each request to the webhook")
+	flag.IntVar(&(cfg.WebHookRetries), "webhook-retries", notifier.DefaultRetries, "number of times a failed request to the webhook is retried")
 	flag.StringVar(&(cfg.ChannelOverride), "channel", "", "Slack channel (missing means use the default channel for this webhook)")
+	flag.Float64Var(&(cfg.GoodCoverage), "good", notifier.DefaultGoodCoverage, "coverage above which a package is shown as good by the webhook and the badges")
+	flag.Float64Var(&(cfg.WarningCoverage), "warning", notifier.DefaultWarningCoverage, "coverage above which a package is shown as a warning (otherwise danger) by the webhook and the badges")
 	flag.StringVar(&(cfg.Prefix), "prefix", "", "prefix is the directory structure to be removed from all package names (makes the output cleaner)")
 	flag.IntVar(&(cfg.Depth), "depth", 0, "How many levels of coverage to output (default is 0 = all)")
 	flag.IntVar(&(cfg.MinCoverage), "m", 0, "minimum coverage")
 	flag.StringVar(&(cfg.Tags), "tags", ``, "go build tags to be added in go test calls")
 	flag.BoolVar(&(cfg.Race), "r", false, "enable race detection during testing")
+	flag.StringVar(&(cfg.CoverMode), "covermode", "", "go test covermode: set, count or atomic (default is the go test default)")
+	flag.StringVar(&(cfg.Uncovered), "uncovered", "", "also print the uncovered source ranges (file:line:column with source) of all packages (all) or of the packages below -m (below)")
+	flag.IntVar(&(cfg.UncoveredContext), "uncovered-context", 2, "how many lines of source to print before and after each uncovered range (used with -uncovered)")
+	flag.IntVar(&(cfg.HotPaths), "hot", 0, "print this many of the most and least executed blocks and functions of each package (requires -covermode=count or atomic)")
+	flag.StringVar(&(cfg.CoverPkg), "coverpkg", "", "calculate the coverage of the packages matching this pattern (e.g. ./...) from the tests of every directory (passed to go test)")
+	flag.DurationVar(&(cfg.Timeout), "timeout", 0, "maximum duration of the tests of each directory (passed to go test); go test and the test binary are killed when they do not stop (0 means go test's own default timeout applies and go test is never killed)")
+	flag.IntVar(&(cfg.Slowest), "slowest", 0, "print this many of the slowest packages (by wall-clock duration of go test) with the test results")
+	flag.StringVar(&(cfg.CacheDir), "cache", "", "reuse the coverage of directories whose sources (and the sources of their dependencies) are unchanged from this cache directory")
+	flag.StringVar(&(cfg.Shard), "shard", "", "only test shard i of n (e.g. 2/4) of the directories (balanced using -timings when supplied)")
+	flag.StringVar(&(cfg.TimingsFile), "timings", "", "durations of a previous run (see -timings-save) used to balance the shards")
+	flag.StringVar(&(cfg.TimingsSave), "timings-save", "", "save how long each directory took to test to this file (when merging shards, the combined durations)")
+	flag.StringVar(&(cfg.ShardOutput), "shard-save", "", "save the coverage, test results and timings of this shard into this directory (to be combined with -shard-merge)")
+	flag.StringVar(&(cfg.ShardMerge), "shard-merge", "", "comma separated list of shard directories (glob patterns, see -shard-save) to combine into the report instead of calculating the coverage")
+	flag.StringVar(&(cfg.MergeProfiles), "merge", "", "comma separated list of additional coverage profiles (glob patterns) to merge into all outputs (e.g. from integration tests)")
+	flag.BoolVar(&(cfg.PrintAttribution), "attribution", false, "also print which tests contributed the coverage of each package (use with -coverpkg)")
+	flag.StringVar(&(cfg.JSONOutput), "json", "", "write the per-package coverage as JSON to this file (use - for stdout)")
+	flag.StringVar(&(cfg.CoberturaOutput), "cobertura", "", "write the coverage as Cobertura XML to this file (use - for stdout)")
+	flag.StringVar(&(cfg.LCOVOutput), "lcov", "", "write the coverage as an LCOV tracefile to this file (use - for stdout)")
+	flag.StringVar(&(cfg.JUnitOutput), "junit", "", "write the results of the tests as JUnit XML to this file (use - for stdout)")
+	flag.StringVar(&(cfg.MarkdownOutput), "markdown", "", "write a Markdown summary of the coverage (e.g. for pull request comments) to this file (use - for stdout)")
+	flag.StringVar(&(cfg.MarkdownBaseline), "markdown-baseline", "", "coverage profile to compare the Markdown summary to (e.g. from the main branch)")
+	flag.StringVar(&(cfg.BadgeOutput), "badges", "", "write SVG coverage badges for the total and each package (up to -depth) into this directory")
+	flag.StringVar(&(cfg.TreemapOutput), "treemap", "", "write the coverage as an SVG treemap (area is statements, color is coverage) to this file (use - for stdout)")
+	flag.StringVar(&(cfg.HTMLOutput), "html", "", "write a static HTML coverage report into this directory")
+	flag.StringVar(&(cfg.Baseline), "baseline", "", "compare the coverage against this previously saved baseline file and fail on regressions")
+	flag.StringVar(&(cfg.BaselineSave), "baseline-save", "", "save the per-package coverage to this file for use as a baseline")
+	flag.Float64Var(&(cfg.BaselineTolerance), "tolerance", 0, "how many percent a package's coverage can drop before it is considered a regression of the baseline")
+	flag.StringVar(&(cfg.DiffBase), "diff", "", "output the coverage of the go code changed since this git ref (e.g. origin/master)")
+	flag.IntVar(&(cfg.DiffMinCoverage), "diff-m", 0, "minimum coverage of the changed statements (used with -diff)")
 	flag.BoolVar(&(cfg.DoAll), "a", true, "short form/convenience method for -c -p -d (calculate, output and clean up)")
 	flag.Parse()
 
@@ -111,11 +277,44 @@ func GetConfig() *Config {
 	}
 
 	// validate config
-	if cfg.Depth > 0 && len(cfg.Prefix) == 0 {
-		println("You must specify a prefix when using -depth")
+	switch cfg.CoverMode {
+	case "", "set", "count", "atomic":
+
+	default:
+		println("-covermode must be one of set, count or atomic")
+		os.Exit(-1)
+	}
+
+	switch cfg.Uncovered {
+	case "", "all", "below":
+
+	default:
+		println("-uncovered must be one of all or below")
 		os.Exit(-1)
 	}
 
+	switch cfg.Notifier {
+	case "slack", "teams":
+
+	case "template":
+		if cfg.WebHook != "" && cfg.WebHookTemplate == "" {
+			println("-notifier=template requires -webhook-template")
+			os.Exit(-1)
+		}
+
+	default:
+		println("-notifier must be one of slack, teams or template")
+		os.Exit(-1)
+	}
+
+	if cfg.Shard != "" {
+		_, err := fmt.Sscanf(cfg.Shard, "%d/%d", &cfg.ShardIndex, &cfg.ShardCount)
+		if err != nil || cfg.ShardCount < 1 || cfg.ShardIndex < 1 || cfg.ShardIndex > cfg.ShardCount {
+			println("-shard must be i/n where 1 <= i <= n (e.g. 2/4)")
+			os.Exit(-1)
+		}
+	}
+
 	// Set "default" mode (Calculate+Print+Clean up) when selected
 	if cfg.DoAll {
 		cfg.Coverage = true
@@ -123,5 +322,27 @@ func GetConfig() *Config {
 		cfg.DoClean = true
 	}
 
+	// the coverage was calculated by the shards
+	if cfg.ShardMerge != "" {
+		cfg.Coverage = false
+	}
+
+	// machine-readable output to StdOut moves the console output to StdErr so that the output remains parsable
+	stdOutOutputs := 0
+	for _, output := range []string{cfg.JSONOutput, cfg.CoberturaOutput, cfg.LCOVOutput, cfg.JUnitOutput, cfg.MarkdownOutput, cfg.TreemapOutput} {
+		if output == "-" {
+			stdOutOutputs++
+		}
+	}
+
+	switch {
+	case stdOutOutputs > 1:
+		println("only one of -json, -cobertura, -lcov, -junit, -markdown and -treemap can be written to stdout (-)")
+		os.Exit(-1)
+
+	case stdOutOutputs == 1:
+		cfg.MachineOutput = true
+	}
+
 	return cfg
 }
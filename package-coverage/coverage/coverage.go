@@ -0,0 +1,71 @@
+// Copyright 2017 Corey Scott http://www.sage42.org/
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package coverage calculates the test coverage of a tree of packages for use as a library.
+// The package-coverage command is built from the same parts (see the generator and parser packages).
+package coverage
+
+import (
+	"context"
+
+	"github.com/corsc/go-tools/package-coverage/generator"
+	"github.com/corsc/go-tools/package-coverage/parser"
+)
+
+// Options controls how the coverage is calculated
+type Options struct {
+	// Generator controls how the tests are run (BasePath is the directory to calculate the coverage of; set QuietMode
+	// to suppress the output of go test)
+	generator.Generator
+
+	// SingleDir only calculates the coverage of BasePath (rather than of every directory below it)
+	SingleDir bool
+
+	// KeepFiles leaves the coverage and test results files in their output directory (see utils.GetOutputDir; by
+	// default they are removed)
+	KeepFiles bool
+
+	// Profiles are additional coverage profiles (filepath.Glob patterns, e.g. from integration tests) to merge into the
+	// report (optional)
+	Profiles []string
+}
+
+// Run will run the tests of the directories under the base path and return the coverage of each package.
+// Failing tests are not an error; their results are included in the report.
+// When the context is cancelled, the running tests are stopped and the context's error is returned.
+func Run(ctx context.Context, options Options) (*parser.Report, error) {
+	var generatorDo generator.GeneratorDo
+	if options.SingleDir {
+		options.Concurrency = 1
+		generatorDo = &generator.SingleDirGenerator{Generator: options.Generator}
+	} else {
+		generatorDo = &generator.RecursiveGenerator{Generator: options.Generator}
+	}
+
+	err := generatorDo.Run(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if !options.KeepFiles {
+		defer generator.Clean(options.BasePath, options.Exclusion, options.SingleDir)
+	}
+
+	return parser.Load(ctx, parser.LoadOptions{
+		BasePath:   options.BasePath,
+		Exclusions: options.Exclusion,
+		SingleDir:  options.SingleDir,
+		Profiles:   options.Profiles,
+	})
+}
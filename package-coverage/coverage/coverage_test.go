@@ -0,0 +1,132 @@
+// Copyright 2017 Corey Scott http://www.sage42.org/
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package coverage
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/corsc/go-tools/package-coverage/generator"
+	"github.com/corsc/go-tools/package-coverage/parser"
+	"github.com/corsc/go-tools/package-coverage/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+const testPkg = "github.com/corsc/go-tools/package-coverage/test-data/pathmatcher/"
+
+func TestRun(t *testing.T) {
+	if testing.Short() {
+		t.Skip("runs go test")
+	}
+
+	basePath, err := filepath.Abs("../test-data/pathmatcher")
+	assert.NoError(t, err)
+
+	report, err := Run(context.Background(), Options{
+		Generator: generator.Generator{
+			BasePath:  basePath,
+			QuietMode: true,
+		},
+	})
+	assert.NoError(t, err)
+
+	// the sub-packages have no statements
+	assert.Len(t, report.Packages, 1)
+
+	pkg := report.Find(testPkg)
+	if assert.NotNil(t, pkg) {
+		assert.Equal(t, report.Packages, report.Roots)
+		assert.Equal(t, 100.0, pkg.Branch().Percent())
+		assert.Equal(t, 0, pkg.Tests.Failed)
+		assert.False(t, pkg.TimedOut)
+	}
+
+	// nothing is written to the source tree and the output is removed
+	_, err = os.Stat(filepath.Join(basePath, "profile.cov"))
+	assert.True(t, os.IsNotExist(err))
+
+	outputDir, err := utils.GetOutputDir(basePath)
+	assert.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(filepath.Dir(filepath.Clean(outputDir)))
+	}()
+
+	_, err = os.Stat(outputDir)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestRun_DirectoriesWithoutTests(t *testing.T) {
+	if testing.Short() {
+		t.Skip("runs go test")
+	}
+
+	basePath, err := ioutil.TempDir("", "notests")
+	assert.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(basePath)
+	}()
+
+	files := map[string]string{
+		"go.mod":                 "module example.com/notests\n\ngo 1.20\n",
+		"code.go":                "package notests\n\nfunc A() int {\n\treturn 1\n}\n",
+		"onlytests/only_test.go": "package onlytests\n\nimport \"testing\"\n\nfunc TestA(t *testing.T) {}\n",
+	}
+	for filename, contents := range files {
+		assert.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(basePath, filename)), 0700))
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(basePath, filename), []byte(contents), 0600))
+	}
+
+	report, err := Run(context.Background(), Options{
+		Generator: generator.Generator{
+			BasePath:  basePath,
+			QuietMode: true,
+		},
+	})
+	assert.NoError(t, err)
+
+	// directories without tests are tested with the fake test (so their code is reported as uncovered)
+	pkg := report.Find("example.com/notests/")
+	if assert.NotNil(t, pkg) {
+		assert.Equal(t, parser.Counts{Statements: 1, Covered: 0}, pkg.Self)
+	}
+
+	// directories with only tests have no statements (and are therefore not reported)
+	assert.Nil(t, report.Find("example.com/notests/onlytests/"))
+
+	// the fake test is never written to the source tree
+	_, err = os.Stat(filepath.Join(basePath, "fake_test.go"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestRun_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	basePath, err := filepath.Abs("../test-data/pathmatcher")
+	assert.NoError(t, err)
+
+	report, err := Run(ctx, Options{
+		Generator: generator.Generator{
+			BasePath:  basePath,
+			QuietMode: true,
+		},
+		SingleDir: true,
+	})
+	assert.Equal(t, context.Canceled, err)
+	assert.Nil(t, report)
+}
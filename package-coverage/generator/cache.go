@@ -0,0 +1,378 @@
+// Copyright 2017 Corey Scott http://www.sage42.org/
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generator
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+
+	"github.com/corsc/go-commons/iocloser"
+	"github.com/corsc/go-tools/package-coverage/utils"
+)
+
+// bump when the contents of the key or of the cached files change
+const cacheVersion = "1"
+
+// cachedFilenames are the files (generated by go test in each directory) that are stored in the cache
+var cachedFilenames = []string{coverageFilename, utils.TestResultsFilename}
+
+// cache reuses the coverage profile and test results of a directory when neither its sources nor the sources of its
+// (transitive) dependencies have changed since they were cached.
+// The key of each directory is a hash of these sources, the go version and environment and the test options.
+// NOTE: only successful runs are cached and anything else the tests depend on (e.g. environment variables or
+// external services) is not part of the key.
+type cache struct {
+	dir string
+
+	// environment is the go version and environment the tests are built with
+	environment string
+
+	hits   int32
+	misses int32
+}
+
+// create a cache in the supplied directory (a nil cache caches nothing)
+func newCache(dir string) (*cache, error) {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	environment, err := exec.Command("go", "env", "GOVERSION", "GOOS", "GOARCH", "GOFLAGS", "CGO_ENABLED", "GOEXPERIMENT").Output()
+	if err != nil {
+		return nil, err
+	}
+
+	return &cache{
+		dir:         dir,
+		environment: string(environment),
+	}, nil
+}
+
+// cachePackage is the subset of the go list -json output used to calculate the key
+type cachePackage struct {
+	ImportPath string
+	Dir        string
+	Standard   bool
+	Module     *cacheModule
+
+	GoFiles    []string
+	CgoFiles   []string
+	CFiles     []string
+	CXXFiles   []string
+	HFiles     []string
+	SFiles     []string
+	SysoFiles  []string
+	EmbedFiles []string
+
+	TestGoFiles     []string
+	XTestGoFiles    []string
+	TestEmbedFiles  []string
+	XTestEmbedFiles []string
+}
+
+type cacheModule struct {
+	Path    string
+	Version string
+	Main    bool
+	Replace *cacheModule
+}
+
+// returns the key of the supplied directory ("" means the directory cannot be cached)
+func (c *cache) getKey(ctx context.Context, dir string, options testOptions) string {
+	if c == nil {
+		return ""
+	}
+
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		utils.LogWhenVerbose("[cache] unable to calculate key for %s. err: %s", dir, err)
+		return ""
+	}
+
+	pkgs, err := listDependencies(ctx, dir, options)
+	if err != nil {
+		utils.LogWhenVerbose("[cache] unable to list the dependencies of %s. err: %s", dir, err)
+		return ""
+	}
+
+	hasher := sha256.New()
+	writeHashEntry(hasher, "version", cacheVersion)
+	writeHashEntry(hasher, "environment", c.environment)
+	writeHashEntry(hasher, "dir", absDir)
+	writeHashEntry(hasher, "tags", options.tags)
+	writeHashEntry(hasher, "race", fmt.Sprint(options.race))
+	writeHashEntry(hasher, "covermode", options.coverMode)
+	writeHashEntry(hasher, "coverpkg", options.coverPkg)
+
+	for _, pkg := range pkgs {
+		err = hashPackage(hasher, pkg, filepath.Clean(pkg.Dir) == filepath.Clean(absDir))
+		if err != nil {
+			utils.LogWhenVerbose("[cache] unable to hash package %s. err: %s", pkg.ImportPath, err)
+			return ""
+		}
+	}
+
+	return hex.EncodeToString(hasher.Sum(nil))
+}
+
+// list the (transitive) dependencies of the package and its tests in the supplied directory (along with the packages
+// coverage is calculated for)
+func listDependencies(ctx context.Context, dir string, options testOptions) ([]*cachePackage, error) {
+	arguments := []string{"list", "-deps", "-test", "-json"}
+
+	if len(options.tags) > 0 {
+		arguments = append(arguments, `-tags=`+options.tags)
+	}
+
+	arguments = append(arguments, ".")
+
+	if len(options.coverPkg) > 0 {
+		arguments = append(arguments, strings.Split(getCoverPkg(options.coverPkg, dir), ",")...)
+	}
+
+	stderr := &bytes.Buffer{}
+
+	cmd := exec.CommandContext(ctx, "go", arguments...)
+	cmd.Dir = dir
+	cmd.Env = getTestEnv(dir, options)
+	cmd.Stderr = stderr
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", err, stderr)
+	}
+
+	var pkgs []*cachePackage
+	seen := map[string]struct{}{}
+
+	decoder := json.NewDecoder(bytes.NewReader(output))
+	for decoder.More() {
+		pkg := &cachePackage{}
+
+		err = decoder.Decode(pkg)
+		if err != nil {
+			return nil, err
+		}
+
+		// the test variants (e.g. "fu [fu.test]") contain the same files as the package
+		if _, found := seen[pkg.Dir]; found {
+			continue
+		}
+		seen[pkg.Dir] = struct{}{}
+
+		pkgs = append(pkgs, pkg)
+	}
+
+	// sorted so that the key does not depend on the order of the output
+	sort.Slice(pkgs, func(i, j int) bool {
+		return pkgs[i].Dir < pkgs[j].Dir
+	})
+
+	return pkgs, nil
+}
+
+// add the package to the hash.  Tests (and testdata) are only included for the tested package.
+func hashPackage(hasher hash.Hash, pkg *cachePackage, tested bool) error {
+	// covered by the go version
+	if pkg.Standard {
+		return nil
+	}
+
+	// modules from the module cache are immutable
+	if pkg.Module != nil && !pkg.Module.Main && pkg.Module.Replace == nil && pkg.Module.Version != "" {
+		writeHashEntry(hasher, "module", pkg.Module.Path+"@"+pkg.Module.Version)
+		return nil
+	}
+
+	writeHashEntry(hasher, "package", pkg.ImportPath)
+
+	filenames := [][]string{pkg.GoFiles, pkg.CgoFiles, pkg.CFiles, pkg.CXXFiles, pkg.HFiles, pkg.SFiles, pkg.SysoFiles, pkg.EmbedFiles}
+	if tested {
+		filenames = append(filenames, pkg.TestGoFiles, pkg.XTestGoFiles, pkg.TestEmbedFiles, pkg.XTestEmbedFiles)
+	}
+
+	for _, group := range filenames {
+		for _, filename := range group {
+			err := hashFile(hasher, filepath.Join(pkg.Dir, filename))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if !tested {
+		return nil
+	}
+
+	return hashTestData(hasher, filepath.Join(pkg.Dir, "testdata"))
+}
+
+// add every file in the testdata directory (which is commonly read by the tests) to the hash
+func hashTestData(hasher hash.Hash, dir string) error {
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return nil
+	}
+
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		return hashFile(hasher, path)
+	})
+}
+
+func hashFile(hasher hash.Hash, filename string) error {
+	file, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+
+	defer iocloser.Close(file)
+
+	fileHasher := sha256.New()
+	_, err = io.Copy(fileHasher, file)
+	if err != nil {
+		return err
+	}
+
+	writeHashEntry(hasher, "file", filename+" "+hex.EncodeToString(fileHasher.Sum(nil)))
+	return nil
+}
+
+func writeHashEntry(hasher hash.Hash, name string, value string) {
+	_, _ = fmt.Fprintf(hasher, "%s\x00%s\n", name, value)
+}
+
+// copy the cached files for the key into the directory.  Returns false (a miss) when they are not cached.
+func (c *cache) restore(key string, dir string) bool {
+	if c == nil {
+		return false
+	}
+
+	if key == "" {
+		atomic.AddInt32(&c.misses, 1)
+		return false
+	}
+
+	entry := filepath.Join(c.dir, key)
+	for _, filename := range cachedFilenames {
+		err := copyFile(filepath.Join(entry, filename), filepath.Join(dir, filename))
+		if err != nil {
+			if !os.IsNotExist(err) {
+				utils.LogAlways("[cache] unable to restore %s from the cache. err: %s", dir, err)
+			}
+
+			atomic.AddInt32(&c.misses, 1)
+			return false
+		}
+	}
+
+	utils.LogWhenVerbose("[cache] reused cached coverage for %s", dir)
+	atomic.AddInt32(&c.hits, 1)
+	return true
+}
+
+// save the files generated in the directory under the key
+func (c *cache) save(key string, dir string) {
+	if c == nil || key == "" {
+		return
+	}
+
+	// the entry is built in a temporary directory so that partial entries are never used
+	tempDir, err := ioutil.TempDir(c.dir, key+"-")
+	if err != nil {
+		utils.LogAlways("[cache] unable to save %s to the cache. err: %s", dir, err)
+		return
+	}
+
+	defer func() {
+		_ = os.RemoveAll(tempDir)
+	}()
+
+	for _, filename := range cachedFilenames {
+		err = copyFile(filepath.Join(dir, filename), filepath.Join(tempDir, filename))
+		if err != nil {
+			utils.LogAlways("[cache] unable to save %s to the cache. err: %s", dir, err)
+			return
+		}
+	}
+
+	err = os.Rename(tempDir, filepath.Join(c.dir, key))
+	if err != nil {
+		// most likely saved by a concurrent run
+		utils.LogWhenVerbose("[cache] unable to save %s to the cache. err: %s", dir, err)
+	}
+}
+
+// log how many of the directories were reused from the cache
+func (c *cache) report() {
+	if c == nil {
+		return
+	}
+
+	hits := atomic.LoadInt32(&c.hits)
+	total := hits + atomic.LoadInt32(&c.misses)
+
+	utils.LogAlways("[cache] %d of %d directories reused from the cache (%.2f%% hit rate); %d tested",
+		hits, total, getHitRate(hits, total), total-hits)
+}
+
+func getHitRate(hits int32, total int32) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(hits) / float64(total) * 100
+}
+
+func copyFile(source string, destination string) error {
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+
+	defer iocloser.Close(in)
+
+	out, err := os.Create(destination)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(out, in)
+	if err != nil {
+		_ = out.Close()
+		return err
+	}
+
+	return out.Close()
+}
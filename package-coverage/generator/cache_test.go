@@ -0,0 +1,122 @@
+// Copyright 2017 Corey Scott http://www.sage42.org/
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generator
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/corsc/go-tools/package-coverage/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCacheSaveAndRestore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cache")
+	assert.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+
+	cached, err := newCache(filepath.Join(dir, "cache"))
+	assert.NoError(t, err)
+
+	source := filepath.Join(dir, "source")
+	destination := filepath.Join(dir, "destination")
+	assert.NoError(t, os.Mkdir(source, 0755))
+	assert.NoError(t, os.Mkdir(destination, 0755))
+
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(source, coverageFilename), []byte("mode: set\n"), 0600))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(source, utils.TestResultsFilename), []byte("{}\n"), 0600))
+
+	// not cached yet
+	assert.False(t, cached.restore("abc", destination))
+
+	cached.save("abc", source)
+	assert.True(t, cached.restore("abc", destination))
+
+	contents, err := ioutil.ReadFile(filepath.Join(destination, coverageFilename))
+	assert.NoError(t, err)
+	assert.Equal(t, "mode: set\n", string(contents))
+
+	// directories that could not be hashed are never cached
+	cached.save("", source)
+	assert.False(t, cached.restore("", destination))
+
+	assert.Equal(t, int32(1), cached.hits)
+	assert.Equal(t, int32(2), cached.misses)
+}
+
+func TestCacheGetKey(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cache")
+	assert.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+
+	cached, err := newCache(dir)
+	assert.NoError(t, err)
+
+	path := "../test-data/pathmatcher/"
+
+	key := cached.getKey(context.Background(), path, testOptions{})
+	assert.Len(t, key, 64)
+	assert.Equal(t, key, cached.getKey(context.Background(), path, testOptions{}))
+
+	assert.NotEqual(t, key, cached.getKey(context.Background(), path, testOptions{race: true}))
+	assert.NotEqual(t, key, cached.getKey(context.Background(), path, testOptions{coverMode: "count"}))
+	assert.NotEqual(t, key, cached.getKey(context.Background(), "../test-data/pathmatcher/included/", testOptions{}))
+}
+
+func TestCacheNil(t *testing.T) {
+	var cached *cache
+
+	assert.Equal(t, "", cached.getKey(context.Background(), "../test-data/pathmatcher/", testOptions{}))
+	assert.False(t, cached.restore("abc", "../test-data/pathmatcher/"))
+	cached.save("abc", "../test-data/pathmatcher/")
+	cached.report()
+}
+
+func TestListDependencies_Tags(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cache")
+	assert.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/fu\n\ngo 1.16\n"), 0600))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "fu.go"), []byte("package fu\n"), 0600))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "tagged.go"),
+		[]byte("//go:build integration\n\npackage fu\n\nimport _ \"encoding/xml\"\n"), 0600))
+
+	hasXML := func(pkgs []*cachePackage) bool {
+		for _, pkg := range pkgs {
+			if pkg.ImportPath == "encoding/xml" {
+				return true
+			}
+		}
+		return false
+	}
+
+	pkgs, err := listDependencies(context.Background(), dir, testOptions{})
+	assert.NoError(t, err)
+	assert.False(t, hasXML(pkgs))
+
+	pkgs, err = listDependencies(context.Background(), dir, testOptions{tags: "integration,other"})
+	assert.NoError(t, err)
+	assert.True(t, hasXML(pkgs))
+}
@@ -21,15 +21,19 @@
 package generator
 
 import (
+	"context"
+	"fmt"
 	"regexp"
 
 	"github.com/corsc/go-tools/package-coverage/config"
 )
 
-// Calculate test coverage
-func Calculate(cfg *config.Config, path string, exclusions *regexp.Regexp) {
+// Calculate test coverage.
+// When the context is cancelled (e.g. the run was interrupted), the running tests are stopped and the files created so
+// far are removed.
+func Calculate(ctx context.Context, cfg *config.Config, path string, exclusions *regexp.Regexp) error {
 	if !cfg.Coverage {
-		return
+		return nil
 	}
 
 	var generatorDo GeneratorDo
@@ -39,9 +43,15 @@ func Calculate(cfg *config.Config, path string, exclusions *regexp.Regexp) {
 			Generator: Generator{
 				BasePath:    path,
 				Exclusion:   exclusions,
-				QuietMode:   cfg.Quiet,
+				QuietMode:   cfg.Quiet || cfg.MachineOutput,
 				Race:        cfg.Race,
 				Tags:        cfg.Tags,
+				CoverMode:   cfg.CoverMode,
+				CoverPkg:    cfg.CoverPkg,
+				Timeout:     cfg.Timeout,
+				CacheDir:    cfg.CacheDir,
+				TimingsSave: cfg.TimingsSave,
+				ShardOutput: cfg.ShardOutput,
 				Concurrency: 1,
 			},
 		}
@@ -50,13 +60,27 @@ func Calculate(cfg *config.Config, path string, exclusions *regexp.Regexp) {
 			Generator: Generator{
 				BasePath:    path,
 				Exclusion:   exclusions,
-				QuietMode:   cfg.Quiet,
+				QuietMode:   cfg.Quiet || cfg.MachineOutput,
 				Race:        cfg.Race,
 				Tags:        cfg.Tags,
+				CoverMode:   cfg.CoverMode,
+				CoverPkg:    cfg.CoverPkg,
+				Timeout:     cfg.Timeout,
+				CacheDir:    cfg.CacheDir,
+				ShardIndex:  cfg.ShardIndex,
+				ShardCount:  cfg.ShardCount,
+				TimingsFile: cfg.TimingsFile,
+				TimingsSave: cfg.TimingsSave,
+				ShardOutput: cfg.ShardOutput,
 				Concurrency: cfg.Concurrency,
 			},
 		}
 	}
 
-	generatorDo.Do()
+	err := generatorDo.Run(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to calculate coverage: %w", err)
+	}
+
+	return nil
 }
@@ -16,75 +16,34 @@ package generator
 
 import (
 	"os"
+	"path/filepath"
 	"regexp"
 
 	"github.com/corsc/go-tools/package-coverage/utils"
 )
 
-// NewCleaner returns an instance of the default Cleaner implmentation
-func NewCleaner() Cleaner {
-	return &cleanerImpl{
-		fsWrapper: &fsWrapperImpl{},
+// Clean will remove the files created while calculating the coverage of the supplied path: exactly the files recorded
+// in the journal when there is one, otherwise the output directory (see utils.GetOutputDir) containing the coverage
+// and test results.
+// NOTE: the exclusions and single directory mode no longer matter as nothing is written to the source tree; they are
+// kept for compatibility.
+func Clean(path string, exclusions *regexp.Regexp, singleDir bool) {
+	if removeJournaled(path) {
+		return
 	}
-}
-
-// Cleaner will remove any previously generated coverage files
-type Cleaner interface {
-	// Clean a single directory
-	Single(path string)
-
-	// Recursive will clean a directory and all child directories (excluding any matched be the regex)
-	Recursive(path string, exclusions *regexp.Regexp)
-}
-
-// default implementation of the Cleaner interface
-type cleanerImpl struct {
-	fsWrapper fsWrapper
-}
-
-// Single implements the Cleaner interface
-func (cleaner *cleanerImpl) Single(path string) {
-	cleaner.clean(path)
-}
-
-// Clean will search the supplied directory and any sub-directories that contain Go files and remove any
-// existing coverage files
-func (cleaner *cleanerImpl) Recursive(path string, exclusions *regexp.Regexp) {
-	processAllDirs(path, exclusions, "clean", cleaner.clean)
-}
-
-func (cleaner *cleanerImpl) clean(path string) {
-	coverageFile := path + coverageFilename
 
-	if cleaner.fsWrapper.Exists(coverageFile) {
-		utils.LogWhenVerbose("[cleaner] removing coverage file @ %s", coverageFile)
-		cleaner.fsWrapper.Delete(coverageFile)
-	}
-}
-
-type fsWrapper interface {
-	// Returns true if a file exists and false otherwise
-	Exists(filename string) bool
-
-	// Remove a file
-	Delete(filename string)
-}
-
-// default implementation of fsWrapper
-type fsWrapperImpl struct{}
-
-// Exists implements fsWrapper
-func (fs *fsWrapperImpl) Exists(filename string) bool {
-	if _, err := os.Stat(filename); err == nil {
-		return true
+	outputDir, err := utils.GetOutputDir(path)
+	if err != nil {
+		utils.LogAlways("[cleaner] unable to find the output directory. err: %s", err)
+		return
 	}
-	return false
-}
+	utils.LogWhenVerbose("[cleaner] removing output directory @ %s", outputDir)
 
-// Delete implements fsWrapper
-func (fs *fsWrapperImpl) Delete(filename string) {
-	err := os.Remove(filename)
+	err = os.RemoveAll(outputDir)
 	if err != nil {
-		utils.LogWhenVerbose("[cleaner] failed to remove %s with err: %s", filename, err)
+		utils.LogWhenVerbose("[cleaner] failed to remove %s with err: %s", outputDir, err)
 	}
+
+	// the work directory is only removed when empty
+	_ = os.Remove(filepath.Dir(filepath.Clean(outputDir)))
 }
@@ -15,63 +15,33 @@
 package generator
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 
+	"github.com/corsc/go-tools/package-coverage/utils"
 	"github.com/stretchr/testify/assert"
-	"github.com/stretchr/testify/mock"
 )
 
-func TestCleanerImpl_Single(t *testing.T) {
-	path := "./test-data/"
+func TestClean(t *testing.T) {
+	dir, err := ioutil.TempDir("", "clean")
+	assert.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
 
-	scenarios := []struct {
-		desc      string
-		setupMock func(mockFs *mockFsWrapper)
-	}{
-		{
-			desc: "file exists, delete should be called",
-			setupMock: func(mockFs *mockFsWrapper) {
-				mockFs.On("Exists", path+coverageFilename).Once().Return(true)
-				mockFs.On("Delete", path+coverageFilename).Once()
-			},
-		},
-		{
-			desc: "file does not exist, delete should not be called",
-			setupMock: func(mockFs *mockFsWrapper) {
-				mockFs.On("Exists", path+coverageFilename).Once().Return(false)
-			},
-		},
-	}
+	untouched := filepath.Join(dir, coverageFilename)
+	assert.NoError(t, ioutil.WriteFile(untouched, []byte("mode: set\n"), 0600))
 
-	for _, scenario := range scenarios {
-		cleaner, mockFs := newTestCleaner()
-		scenario.setupMock(mockFs)
+	outputDir := getTestWorkDir(t, dir) + "output/"
 
-		cleaner.Single(path)
-		assert.True(t, mockFs.AssertExpectations(t), scenario.desc)
-	}
-}
-
-func newTestCleaner() (Cleaner, *mockFsWrapper) {
-	mockFs := &mockFsWrapper{}
-
-	return &cleanerImpl{
-		fsWrapper: mockFs,
-	}, mockFs
-}
-
-// mock implementation of the fsWrapper
-type mockFsWrapper struct {
-	mock.Mock
-}
-
-// Exists implements fsWrapper
-func (fs *mockFsWrapper) Exists(filename string) bool {
-	outputs := fs.Mock.Called(filename)
-	return outputs.Bool(0)
-}
+	outputPath := utils.GetOutputPath(outputDir, dir, filepath.Join(dir, "fu"))
+	assert.NoError(t, os.MkdirAll(outputPath, 0700))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(outputPath, coverageFilename), []byte("mode: set\n"), 0600))
 
-// Delete implements fsWrapper
-func (fs *mockFsWrapper) Delete(filename string) {
-	fs.Mock.Called(filename)
+	// without a journal, the output directory is removed (and the source tree is never touched)
+	Clean(dir, nil, false)
+	assertFileNotExists(t, outputDir)
+	assertFileExists(t, untouched)
 }
@@ -16,6 +16,9 @@ package generator
 
 import (
 	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
 	"go/parser"
 	"go/token"
@@ -25,6 +28,7 @@ import (
 	"path/filepath"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/corsc/go-commons/iocloser"
 	"github.com/corsc/go-tools/package-coverage/utils"
@@ -32,72 +36,112 @@ import (
 
 const coverageFilename = "profile.cov"
 
+// events can include long lines of test output
+const maxTestEventSize = 10 * 1024 * 1024
+
 var fakeTestFilename = "fake_test.go"
-var fakeCodeFilename = "fake_code.go"
 
-func processAllDirs(basePath string, exclusionsMatcher *regexp.Regexp, logTag string, actionFunc func(string)) {
-	paths, err := utils.FindAllGoDirs(basePath)
-	if err != nil {
-		return
-	}
+// how long to wait for go test to stop after being interrupted before it is killed
+const cancelWaitDelay = 5 * time.Second
 
-	for _, path := range paths {
-		if exclusionsMatcher.FindString(path) != "" {
-			utils.LogWhenVerbose("[%s] path '%s' skipped due to skipDir regex '%s'",
-				logTag, path, exclusionsMatcher.String())
-			continue
-		}
+// how long after the timeout (which go test enforces itself) go test is killed
+const timeoutGracePeriod = 30 * time.Second
 
-		utils.LogWhenVerbose("[%s] processing path '%s'", logTag, path)
-		actionFunc(path)
-	}
+// go test -json actions added to the test results by the generator (and not by go test)
+const (
+	// actionRun records the wall-clock duration (build and test) of go test
+	actionRun = "run"
+
+	// actionTimeout records that go test was killed after the timeout
+	actionTimeout = "timeout"
+)
+
+// testOptions are the settings used when running go test
+type testOptions struct {
+	// basePath is the directory the coverage is calculated for (the coverage and test results of each directory are
+	// written below its output directory; see utils.GetOutputDir)
+	basePath string
+
+	// outputDir is the directory the coverage and test results are written to (see utils.GetOutputDir)
+	outputDir string
+
+	quiet bool
+	race  bool
+	tags  string
+
+	// coverMode is the go test -covermode (missing means the go test default)
+	coverMode string
+
+	// coverPkg is the pattern of packages coverage is calculated for (missing means the tested package only)
+	coverPkg string
+
+	// timeout is the maximum duration of the tests of each directory (0 means go test's own default)
+	timeout time.Duration
+
+	// overlayFile is the overlay config containing the fake tests
+	overlayFile string
+
+	// workFile is the go.work file of the workspace (missing means there is no workspace)
+	workFile string
+
+	// workspaceModules are the directories of the modules used by the workspace
+	workspaceModules []string
 }
 
-// this function will generate the test coverage for the supplied directory
-func generateCoverage(path string, exclusions *regexp.Regexp, quietMode, race bool, tags string) {
-	err := execCoverage(path, quietMode, race, tags)
+// this function will generate the test coverage for the supplied directory into its output directory
+// (the unfiltered profile and the test results are reused from, or saved to, the cache)
+func generateCoverage(ctx context.Context, path string, exclusions *regexp.Regexp, options testOptions, cached *cache) {
+	outputPath := utils.GetOutputPath(options.outputDir, options.basePath, path)
+
+	err := os.MkdirAll(outputPath, 0700)
 	if err != nil {
-		utils.LogWhenVerbose("[coverage] error generating coverage %s", err)
+		utils.LogAlways("[coverage] unable to create output directory %s. err: %s", outputPath, err)
+		return
+	}
+
+	key := cached.getKey(ctx, path, options)
+	if !cached.restore(key, outputPath) {
+		err = execCoverage(ctx, path, outputPath, options)
+		if err != nil {
+			utils.LogWhenVerbose("[coverage] error generating coverage %s", err)
+		} else {
+			cached.save(key, outputPath)
+		}
 	}
 
-	err = filterCoverage(filepath.Join(path, coverageFilename), exclusions)
+	err = filterCoverage(filepath.Join(outputPath, coverageFilename), exclusions)
 	if err != nil {
 		utils.LogWhenVerbose("[coverage] error filtering files: %s", err)
 	}
 }
 
-func addFakes(path, packageName string) {
+// add the fake test to the overlay so that the source tree is never modified.
+// NOTE: fake code is not required (go test handles directories with only tests) and cannot be added to the overlay
+// as the cover tool reads the instrumented files directly from disk.
+func addFakes(path, packageName string, fakes *overlay) {
 	testFilename := createTestFilename(path)
-	createTestFile(packageName, testFilename)
-
-	codeFilename := createCodeFilename(path)
-	createCodeFile(packageName, codeFilename)
+	addFake(fakes, testFilename, createTestContents(packageName))
 }
 
-func removeFakes(path string) {
-	testFilename := createTestFilename(path)
-	utils.LogWhenVerbose("[coverage] remove fake test @ %s", testFilename)
-	err := os.Remove(testFilename)
-	if err != nil {
-		utils.LogWhenVerbose("[coverage] error while removing fake test @ %s, err: %s", testFilename, err)
+func addFake(fakes *overlay, filename string, contents string) {
+	if _, err := os.Stat(filename); err == nil {
+		utils.LogWhenVerbose("[coverage] file already exists @ %s cowardly refusing to replace", filename)
+		return
 	}
 
-	codeFilename := createCodeFilename(path)
-	utils.LogWhenVerbose("[coverage] remove fake code @ %s", codeFilename)
-	err = os.Remove(codeFilename)
+	err := fakes.add(filename, contents)
 	if err != nil {
-		utils.LogWhenVerbose("[coverage] error while removing fake code @ %s, err: %s", codeFilename, err)
+		utils.LogWhenVerbose("[coverage] error while creating fake file for %s. err: %s", filename, err)
+		return
 	}
+
+	utils.LogWhenVerbose("[coverage] added fake file @ %s", filename)
 }
 
 func createTestFilename(path string) string {
 	return path + fakeTestFilename
 }
 
-func createCodeFilename(path string) string {
-	return path + fakeCodeFilename
-}
-
 // find the package name by using the go AST
 func findPackageName(path string) string {
 	fileSet := token.NewFileSet()
@@ -126,115 +170,288 @@ func findPackageName(path string) string {
 	return out
 }
 
-// create a fake test so that all directories are guaranteed to contain tests (and therefore coverage will be generated)
-func createTestFile(packageName string, testFilename string) {
-	utils.LogWhenVerbose("[coverage] created test for package %s file @ %s", packageName, testFilename)
+// a fake test so that all directories are guaranteed to contain tests (and therefore coverage will be generated)
+func createTestContents(packageName string) string {
+	return `package ` + packageName + `
 
-	if _, err := os.Stat(testFilename); err == nil {
-		utils.LogWhenVerbose("[coverage] file already exists @ %s cowardly refusing to overwrite", testFilename)
-		return
+import "testing"
+
+func TestThisTestDoesntReallyTestAnything(t *testing.T) {}
+`
+}
+
+// essentially call `go test` to generate the coverage (and the test results as a go test -json event stream) of the
+// directory into the output path
+func execCoverage(ctx context.Context, dir string, outputPath string, options testOptions) error {
+	arguments := []string{
+		"test",
+		"-json",
+		"-coverprofile=" + filepath.Join(outputPath, coverageFilename),
 	}
 
-	file, err := os.OpenFile(testFilename, os.O_RDWR|os.O_CREATE, 0600)
-	if err != nil {
-		utils.LogWhenVerbose("[coverage] error while creating test file %s", err)
-		return
+	if len(options.coverMode) > 0 {
+		arguments = append(arguments, `-covermode=`+options.coverMode)
 	}
 
-	_, err = file.WriteString(`package ` + packageName + `
+	if len(options.coverPkg) > 0 {
+		arguments = append(arguments, `-coverpkg=`+getCoverPkg(options.coverPkg, dir))
+	}
 
-import "testing"
+	if len(options.overlayFile) > 0 {
+		arguments = append(arguments, `-overlay=`+options.overlayFile)
+	}
 
-func TestThisTestDoesntReallyTestAnything(t *testing.T) {}
-`)
+	if options.race {
+		arguments = append(arguments, `--race`)
+	}
+
+	// go test stops the tests itself after the timeout (with a stack trace) and the whole process group is killed
+	// when go test does not stop (e.g. the build hangs)
+	runCtx := ctx
+	if options.timeout > 0 {
+		arguments = append(arguments, `-timeout=`+options.timeout.String())
+
+		var cancel context.CancelFunc
+		runCtx, cancel = context.WithTimeout(ctx, options.timeout+timeoutGracePeriod)
+		defer cancel()
+	}
+
+	if len(options.tags) > 0 {
+		arguments = append(arguments, `-tags=`+options.tags)
+	}
+
+	resultsFilename := filepath.Join(outputPath, utils.TestResultsFilename)
+	resultsFile, err := os.Create(resultsFilename)
 	if err != nil {
-		utils.LogWhenVerbose("[coverage] error while writing test file %s", err)
-		return
+		utils.LogAlways("[coverage] error while creating test results file %s. err: %s", resultsFilename, err)
+		return err
+	}
+
+	defer iocloser.Close(resultsFile)
+
+	cmd := exec.CommandContext(runCtx, "go", arguments...)
+	cmd.Dir = dir
+	cmd.Env = getTestEnv(dir, options)
+
+	// when cancelled, interrupt (rather than kill) go test and the test binary so that neither is orphaned; when the
+	// timeout passed, they are not responding and are killed
+	setProcessGroup(cmd)
+	cmd.Cancel = func() error {
+		if ctx.Err() == nil {
+			utils.LogAlways("[coverage] tests for %s did not finish within %s; killing go test", dir, options.timeout)
+			return killProcessGroup(cmd)
+		}
+		return interruptProcessGroup(cmd)
 	}
+	cmd.WaitDelay = cancelWaitDelay
+
+	// the events are saved for the parser; stderr (e.g. build errors) is kept for the logs
+	payload := &bytes.Buffer{}
+	cmd.Stdout = resultsFile
+	cmd.Stderr = payload
+
+	if !options.quiet {
+		cmd.Stdout = io.MultiWriter(resultsFile, &testOutputWriter{out: os.Stdout})
+		cmd.Stderr = io.MultiWriter(payload, os.Stderr)
+	}
+
+	start := time.Now()
+	err = cmd.Run()
+
+	timedOut := runCtx.Err() == context.DeadlineExceeded && ctx.Err() == nil
+	addRunEvents(resultsFile, dir, options, time.Since(start), timedOut)
 
-	err = file.Close()
 	if err != nil {
-		utils.LogWhenVerbose("[coverage] error while closing file '%s", err)
+		utils.LogAlways("[coverage] test output %s:\n%s", dir, payload)
+
+		if err.Error() == "exit status 1" {
+			utils.LogAlways("WARNING: tests for %s are broken. err: %s", dir, err)
+		} else {
+			utils.LogAlways("[coverage] error while running go test %s. err: %s", dir, err)
+		}
+
+		return err
 	}
+
+	utils.LogWhenVerbose("[coverage] test output %s:\n%s", dir, payload)
+	utils.LogWhenVerbose("[coverage] created coverage file @ %s%s", outputPath, coverageFilename)
+	return nil
 }
 
-// create a fake function so that all directories are guaranteed to contain code (and therefore coverage will be generated)
-func createCodeFile(packageName string, codeFilename string) {
-	utils.LogWhenVerbose("[coverage] created code for package %s file @ %s", packageName, codeFilename)
+// generatorEvent is a go test -json event added by the generator
+type generatorEvent struct {
+	Time    time.Time
+	Action  string
+	Package string
+	Elapsed float64
+	Output  string `json:",omitempty"`
+}
 
-	if _, err := os.Stat(codeFilename); err == nil {
-		utils.LogWhenVerbose("[coverage] file already exists @ %s cowardly refusing to overwrite", codeFilename)
+// add the wall-clock duration of go test (and whether it timed out) to the test results
+func addRunEvents(resultsFile *os.File, dir string, options testOptions, elapsed time.Duration, timedOut bool) {
+	pkg := findTestedPackage(resultsFile.Name(), dir, options)
+	if pkg == "" {
+		utils.LogWhenVerbose("[coverage] unable to find the package tested in %s; duration not recorded", dir)
 		return
 	}
 
-	file, err := os.OpenFile(codeFilename, os.O_RDWR|os.O_CREATE, 0600)
-	if err != nil {
-		utils.LogWhenVerbose("[coverage] error while creating code file %s", err)
-		return
+	events := []generatorEvent{
+		{Time: time.Now(), Action: actionRun, Package: pkg, Elapsed: elapsed.Seconds()},
 	}
 
-	_, err = file.WriteString(`package ` + packageName + `
+	if timedOut {
+		events = append(events, generatorEvent{
+			Time:    time.Now(),
+			Action:  actionTimeout,
+			Package: pkg,
+			Elapsed: elapsed.Seconds(),
+			Output:  fmt.Sprintf("go test did not finish within %s and was killed\n", options.timeout),
+		})
+	}
 
-func thisCodeDoesntReallyDoAnything() {}
-`)
-	if err != nil {
-		utils.LogWhenVerbose("[coverage] error while writing code file %s", err)
-		return
+	encoder := json.NewEncoder(resultsFile)
+	for _, event := range events {
+		err := encoder.Encode(event)
+		if err != nil {
+			utils.LogAlways("[coverage] error while writing test results file %s. err: %s", resultsFile.Name(), err)
+			return
+		}
+	}
+}
+
+// returns the package tested in the directory using the events written by go test (or go list when there are none,
+// e.g. when go test was killed during the build)
+func findTestedPackage(resultsFilename string, dir string, options testOptions) string {
+	file, err := os.Open(resultsFilename)
+	if err == nil {
+		defer iocloser.Close(file)
+
+		scanner := bufio.NewScanner(file)
+		scanner.Buffer(nil, maxTestEventSize)
+
+		for scanner.Scan() {
+			event := generatorEvent{}
+			if json.Unmarshal(scanner.Bytes(), &event) == nil && event.Package != "" {
+				return event.Package
+			}
+		}
 	}
 
-	err = file.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), timeoutGracePeriod)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "go", "list", "-e", "-f", "{{.ImportPath}}", ".")
+	cmd.Dir = dir
+	cmd.Env = getTestEnv(dir, options)
+
+	output, err := cmd.Output()
 	if err != nil {
-		utils.LogWhenVerbose("[coverage] error while closing file '%s", err)
+		return ""
 	}
+
+	return strings.TrimSpace(string(output))
 }
 
-// essentially call `go test` to generate the coverage
-func execCoverage(dir string, quiet, race bool, tags string) error {
-	arguments := []string{
-		"test",
-		"-coverprofile=" + coverageFilename,
+// returns the environment for go test (nil means the current environment).
+// go test runs in the module containing the tested directory; however it refuses to run in modules that are not used
+// by the workspace so these modules are tested without the workspace.
+func getTestEnv(dir string, options testOptions) []string {
+	module := utils.FindModule(dir)
+	if module == nil {
+		utils.LogWhenVerbose("[coverage] directory '%s' is not inside a module", dir)
+		return nil
 	}
 
-	if race {
-		arguments = append(arguments, `--race`)
+	if options.workFile == "" {
+		return nil
 	}
 
-	if len(tags) > 0 {
-		arguments = append(arguments, `-tags='`+tags+`'`)
+	for _, workspaceModule := range options.workspaceModules {
+		if workspaceModule == module.Dir {
+			return nil
+		}
 	}
 
-	cmd := exec.Command("go", arguments...)
-	cmd.Dir = dir
+	utils.LogWhenVerbose("[coverage] module '%s' is not used by workspace '%s'; testing without the workspace", module.Path, options.workFile)
+	return append(os.Environ(), "GOWORK=off")
+}
 
-	if !quiet {
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
+// go test runs in the tested directory so relative patterns (which are relative to the current directory) are converted
+// to be relative to the tested directory
+func getCoverPkg(coverPkg string, dir string) string {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return coverPkg
 	}
 
-	payload, err := cmd.CombinedOutput()
-	if err != nil {
-		utils.LogAlways("failed to get test command output. dir: %s err: %s", dir, err)
+	patterns := strings.Split(coverPkg, ",")
+	for index, pattern := range patterns {
+		if !strings.HasPrefix(pattern, ".") {
+			continue
+		}
+
+		absPattern, err := filepath.Abs(pattern)
+		if err != nil {
+			continue
+		}
+
+		relPattern, err := filepath.Rel(absDir, absPattern)
+		if err != nil {
+			continue
+		}
+
+		// go only treats patterns starting with ./ or ../ as relative
+		if relPattern != "." && relPattern != ".." && !strings.HasPrefix(relPattern, "../") {
+			relPattern = "./" + relPattern
+		}
+
+		patterns[index] = relPattern
 	}
 
-	if err != nil {
-		utils.LogAlways("[coverage] test output %s:\n%s", dir, payload)
+	return strings.Join(patterns, ",")
+}
 
-		if err.Error() == "exit status 1" {
-			utils.LogAlways("WARNING: tests for %s are broken. err: %s", dir, err)
+// testOutputWriter writes the output contained in the go test -json events it receives (i.e. the regular go test output)
+type testOutputWriter struct {
+	out    io.Writer
+	buffer []byte
+}
+
+// Write implements io.Writer
+func (w *testOutputWriter) Write(data []byte) (int, error) {
+	w.buffer = append(w.buffer, data...)
+
+	for {
+		index := bytes.IndexByte(w.buffer, '\n')
+		if index == -1 {
+			return len(data), nil
+		}
+
+		event := struct {
+			Output string
+		}{}
+
+		var err error
+		if json.Unmarshal(w.buffer[:index], &event) == nil {
+			_, err = io.WriteString(w.out, event.Output)
 		} else {
-			utils.LogAlways("[coverage] error while running go test %s. err: %s", dir, err)
+			// not an event; pass through as is
+			_, err = w.out.Write(w.buffer[:index+1])
 		}
 
-		return err
-	} else {
-		utils.LogWhenVerbose("[coverage] test output %s:\n%s", dir, payload)
-	}
+		w.buffer = w.buffer[index+1:]
 
-	utils.LogWhenVerbose("[coverage] created coverage file @ %s%s", dir, coverageFilename)
-	return nil
+		if err != nil {
+			return len(data), err
+		}
+	}
 }
 
 func filterCoverage(coverageFilename string, exclusionsMatcher *regexp.Regexp) error {
+	if exclusionsMatcher == nil {
+		return nil
+	}
+
 	coverageTempFilename := coverageFilename + "~"
 
 	coverageTempFile, err := os.OpenFile(coverageTempFilename, os.O_RDWR|os.O_CREATE, 0600)
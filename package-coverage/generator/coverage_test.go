@@ -15,13 +15,14 @@
 package generator
 
 import (
+	"bytes"
 	"io/ioutil"
 	"log"
 	"os"
 	"regexp"
-	"sort"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/corsc/go-tools/package-coverage/utils"
 	"github.com/stretchr/testify/assert"
@@ -32,53 +33,54 @@ func init() {
 	fakeTestFilename = "my-fake-test.go"
 }
 
-func TestProcessAllDirs(t *testing.T) {
-	dir := strings.TrimSuffix(utils.GetCurrentDir(), "/generator/")
-	tests := []struct {
-		Name    string
-		Exclude string
-		Result  []string
-	}{
-		{
-			Name:    "excludesDir",
-			Exclude: `/excluded/`,
-			Result: []string{
-				dir + "/test-data/pathmatcher/",
-				dir + "/test-data/pathmatcher/included/",
-			},
-		},
-	}
-
-	for _, test := range tests {
-		t.Run(test.Name, func(t *testing.T) {
-			matcher := regexp.MustCompile(test.Exclude)
-			var matched []string
-			processAllDirs("../test-data/pathmatcher", matcher, "processAllDirs", func(path string) {
-				matched = append(matched, path)
-			})
-
-			sort.Strings(test.Result)
-			sort.Strings(matched)
-			assert.Equal(t, test.Result, matched, "expected processed files to match")
-		})
-	}
-
-}
-
 func TestAddFakes_HappyPath(t *testing.T) {
 	path := utils.GetCurrentDir()
 	packageName := "generator"
 
+	fakes, err := newOverlay("")
+	assert.NoError(t, err)
+	defer fakes.remove()
+
+	addFakes(path, packageName, fakes)
+
 	expectedTestFilename := path + fakeTestFilename
-	defer removeTestFile(expectedTestFilename)
 
-	expectedCodeFilename := path + fakeCodeFilename
-	defer removeTestFile(expectedCodeFilename)
+	// the fake should only exist in the overlay
+	assertFileNotExists(t, expectedTestFilename)
 
-	addFakes(path, packageName)
+	assert.Len(t, fakes.Replace, 1)
+	assertFileExists(t, fakes.Replace[expectedTestFilename])
 
-	assertFileExists(t, expectedTestFilename)
-	assertFileExists(t, expectedCodeFilename)
+	overlayFile, err := fakes.save()
+	assert.NoError(t, err)
+	assertFileExists(t, overlayFile)
+
+	fakes.remove()
+	assertFileNotExists(t, overlayFile)
+}
+
+func TestAddFakes_RelativePath(t *testing.T) {
+	fakes, err := newOverlay("")
+	assert.NoError(t, err)
+	defer fakes.remove()
+
+	// the go command resolves relative paths against the directory it is run in (which can differ)
+	addFakes("./", "generator", fakes)
+
+	assert.Len(t, fakes.Replace, 1)
+	assertFileExists(t, fakes.Replace[utils.GetCurrentDir()+fakeTestFilename])
+}
+
+func TestAddFakes_ExistingFileNotReplaced(t *testing.T) {
+	path := utils.GetCurrentDir()
+
+	fakes, err := newOverlay("")
+	assert.NoError(t, err)
+	defer fakes.remove()
+
+	// coverage.go exists so it should never be replaced
+	addFake(fakes, path+"coverage.go", createTestContents("generator"))
+	assert.Empty(t, fakes.Replace)
 }
 
 func TestCreateTestFilename(t *testing.T) {
@@ -97,17 +99,11 @@ func TestExtractPackageName(t *testing.T) {
 	assert.Equal(t, expected, result)
 }
 
-func TestCreateTestFile(t *testing.T) {
-	packageName := "mypackage"
-	testFile := utils.GetCurrentDir() + "my_test.go"
-
-	defer removeTestFile(testFile)
+func TestCreateTestContents(t *testing.T) {
+	result := createTestContents("mypackage")
 
-	assertFileNotExists(t, testFile)
-
-	createTestFile(packageName, testFile)
-
-	assertFileExists(t, testFile)
+	assert.True(t, strings.HasPrefix(result, "package mypackage\n"))
+	assert.Contains(t, result, "func TestThisTestDoesntReallyTestAnything(t *testing.T) {}")
 }
 
 func removeTestFile(path string) {
@@ -164,3 +160,49 @@ github.com/corsc/go-tools/package-coverage/generator/generator.go:16.93,18.2 1 0
 	assert.NoError(t, err)
 	assert.Equal(t, filteredCoverageFile, string(actualCoverageFile))
 }
+
+func TestTestOutputWriter(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	writer := &testOutputWriter{out: buffer}
+
+	// events can be split across writes
+	_, err := writer.Write([]byte(`{"Action":"output","Package":"fu","Output":"=== RUN   TestFu\n"}` + "\n" + `{"Action":"out`))
+	assert.NoError(t, err)
+	_, err = writer.Write([]byte(`put","Package":"fu","Output":"--- PASS: TestFu (0.00s)\n"}` + "\n" + "not an event\n"))
+	assert.NoError(t, err)
+
+	assert.Equal(t, "=== RUN   TestFu\n--- PASS: TestFu (0.00s)\nnot an event\n", buffer.String())
+}
+
+func TestGetCoverPkg(t *testing.T) {
+	dir := utils.GetCurrentDir()
+	parentDir := strings.TrimSuffix(dir, "generator/")
+
+	assert.Equal(t, "./...", getCoverPkg("./...", dir))
+	assert.Equal(t, "./generator/...", getCoverPkg("./...", parentDir))
+	assert.Equal(t, "../...", getCoverPkg("./...", dir+"fu/"))
+	assert.Equal(t, "../utils,github.com/corsc/...", getCoverPkg("../utils,github.com/corsc/...", dir))
+	assert.Equal(t, "./utils", getCoverPkg("../utils", parentDir))
+}
+
+func TestAddRunEvents(t *testing.T) {
+	resultsFile, err := ioutil.TempFile("", "profile.test.json")
+	assert.NoError(t, err)
+	defer func() {
+		_ = os.Remove(resultsFile.Name())
+	}()
+
+	_, err = resultsFile.WriteString(`{"Action":"start","Package":"github.com/corsc/fu"}` + "\n")
+	assert.NoError(t, err)
+
+	addRunEvents(resultsFile, os.TempDir(), testOptions{timeout: time.Second}, 2*time.Second, true)
+	assert.NoError(t, resultsFile.Close())
+
+	contents, err := ioutil.ReadFile(resultsFile.Name())
+	assert.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(string(contents)), "\n")
+	assert.Len(t, lines, 3)
+	assert.Contains(t, lines[1], `"Action":"run","Package":"github.com/corsc/fu","Elapsed":2}`)
+	assert.Contains(t, lines[2], `"Action":"timeout","Package":"github.com/corsc/fu","Elapsed":2,"Output":"go test did not finish within 1s and was killed\n"}`)
+}
@@ -32,10 +32,5 @@ func DoClean(cfg *config.Config, path string, exclusions *regexp.Regexp) {
 		return
 	}
 
-	cleaner := NewCleaner()
-	if cfg.SingleDir {
-		cleaner.Single(path)
-	} else {
-		cleaner.Recursive(path, exclusions)
-	}
+	Clean(path, exclusions, cfg.SingleDir)
 }
@@ -0,0 +1,34 @@
+// Copyright 2017 Corey Scott http://www.sage42.org/
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generator
+
+import (
+	"github.com/corsc/go-tools/package-coverage/config"
+	"github.com/corsc/go-tools/package-coverage/utils"
+)
+
+// DoRecover will remove the files recorded in the journal left by an aborted run.
+// Returns true when recovery was requested (and therefore nothing else should be done)
+func DoRecover(cfg *config.Config, path string) bool {
+	if !cfg.Recover {
+		return false
+	}
+
+	if !removeJournaled(path) {
+		utils.LogAlways("[recover] no journal found for %s; nothing to recover", path)
+	}
+
+	return true
+}
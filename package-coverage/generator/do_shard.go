@@ -0,0 +1,77 @@
+// Copyright 2017 Corey Scott http://www.sage42.org/
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generator
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/corsc/go-tools/package-coverage/config"
+	"github.com/corsc/go-tools/package-coverage/utils"
+)
+
+// DoShardMerge will copy the coverage and test results saved by each shard (see -shard-save) back into the directories
+// they were generated in so that they are reported on (and checked) as if they were calculated by a single run.
+// Returns an error when no shard artifacts were found (as the report would not contain the coverage of the shards).
+func DoShardMerge(cfg *config.Config, path string) error {
+	if cfg.ShardMerge == "" {
+		return nil
+	}
+
+	var artifactDirs []string
+	for _, pattern := range strings.Split(cfg.ShardMerge, ",") {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return fmt.Errorf("invalid shard directory pattern '%s': %w", pattern, err)
+		}
+
+		for _, match := range matches {
+			if info, err := os.Stat(match); err == nil && info.IsDir() {
+				artifactDirs = append(artifactDirs, match)
+			}
+		}
+	}
+
+	if len(artifactDirs) == 0 {
+		return fmt.Errorf("no shard directories found matching '%s'", cfg.ShardMerge)
+	}
+
+	outputDir, err := utils.GetOutputDir(path)
+	if err != nil {
+		return fmt.Errorf("unable to create the work directory. err: %s", err)
+	}
+
+	records, err := openJournal(path)
+	if err != nil {
+		utils.LogAlways("[shard] unable to create journal; merged files will not be recoverable. err: %s", err)
+	}
+	defer records.close()
+
+	combined, merged := mergeShards(outputDir, artifactDirs, records)
+	if merged == 0 {
+		return fmt.Errorf("no coverage or test results found in the shard directories matching '%s'", cfg.ShardMerge)
+	}
+
+	if cfg.TimingsSave != "" {
+		err = saveTimings(cfg.TimingsSave, combined)
+		if err != nil {
+			utils.LogAlways("[shard] unable to save timings to %s. err: %s", cfg.TimingsSave, err)
+		}
+	}
+
+	return nil
+}
@@ -15,9 +15,14 @@
 package generator
 
 import (
+	"context"
+	"fmt"
+	"os"
 	"regexp"
+	"runtime"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/corsc/go-tools/package-coverage/utils"
 )
@@ -27,7 +32,10 @@ const UnknownPackage = "unknown"
 
 // GeneratorDo defines the call API of the generators
 type GeneratorDo interface {
-	Do()
+	// Run will calculate the coverage (and save the test results) of the directories.  When the context is cancelled,
+	// the running tests are stopped, the files created so far are removed and the context's error is returned.
+	// Failing tests are not an error (their results are saved with the coverage).
+	Run(ctx context.Context) error
 }
 
 // SingleDirGenerator will generate coverage for a single directory (not recursive)
@@ -35,9 +43,9 @@ type SingleDirGenerator struct {
 	Generator
 }
 
-// implements pathBuilder interface
-func (g *SingleDirGenerator) Do() {
-	g.do([]string{g.BasePath})
+// implements GeneratorDo interface
+func (g *SingleDirGenerator) Run(ctx context.Context) error {
+	return g.run(ctx, []string{strings.TrimSuffix(g.BasePath, "/") + "/"})
 }
 
 // RecursiveGenerator will recursively generated coverage for a tree of directories
@@ -45,18 +53,18 @@ type RecursiveGenerator struct {
 	Generator
 }
 
-// implements pathBuilder interface
-func (g *RecursiveGenerator) Do() {
+// implements GeneratorDo interface
+func (g *RecursiveGenerator) Run(ctx context.Context) error {
 	paths := []string{}
 	dedupeMap := map[string]struct{}{}
 
 	foundPaths, err := utils.FindAllGoDirs(g.BasePath)
 	if err != nil {
-		return
+		return err
 	}
 
 	for _, path := range foundPaths {
-		if g.Exclusion.FindString(path) != "" {
+		if g.Exclusion != nil && g.Exclusion.FindString(path) != "" {
 			utils.LogWhenVerbose("[coverage] path '%s' skipped due to skipDir regex '%s'", path, g.Exclusion.String())
 			continue
 		}
@@ -74,7 +82,17 @@ func (g *RecursiveGenerator) Do() {
 		}
 	}
 
-	g.do(paths)
+	// only test this shard's directories
+	if g.ShardCount > 0 {
+		known := timings{}
+		if g.TimingsFile != "" {
+			known = loadTimings(g.TimingsFile)
+		}
+
+		paths = selectShard(paths, g.BasePath, g.ShardIndex, g.ShardCount, known)
+	}
+
+	return g.run(ctx, paths)
 }
 
 // Generator is the basis for other coverage generators
@@ -95,28 +113,131 @@ type Generator struct {
 	// Tags is arguments passed to the go test runner
 	Tags string
 
+	// CoverMode is the go test -covermode (set, count or atomic; missing means the go test default)
+	CoverMode string
+
+	// CoverPkg is the pattern of packages to calculate coverage for when running the tests of each directory
+	// (passed to go test as -coverpkg; missing means only the tested package)
+	CoverPkg string
+
+	// Timeout is the maximum duration of the tests of each directory; go test (and the test binary) is killed when
+	// it does not stop (0 means go test's own default timeout applies and go test is never killed)
+	Timeout time.Duration
+
+	// CacheDir is the directory the coverage of unchanged directories is reused from (missing means don't cache)
+	CacheDir string
+
+	// ShardIndex is which (1 based) of the ShardCount shards of the directories to test (only used by the RecursiveGenerator)
+	ShardIndex int
+
+	// ShardCount is the number of shards the directories are split into (0 means test all directories)
+	ShardCount int
+
+	// TimingsFile contains the durations of previous runs used to balance the shards (missing means split evenly)
+	TimingsFile string
+
+	// TimingsSave is the file the durations of this run are saved to (missing means don't save)
+	TimingsSave string
+
+	// ShardOutput is the directory the coverage, test results and timings are saved to for merging later (missing means don't save)
+	ShardOutput string
+
 	// Concurrency controls how many tests can be run concurrently.  Default is `runtime.NumCPU()`
 	Concurrency int
 }
 
-func (g *Generator) do(paths []string) {
+func (g *Generator) run(ctx context.Context, paths []string) error {
 	jobsCh := make(chan string, len(paths))
 	wg := &sync.WaitGroup{}
 
-	// create workers
-	wg.Add(g.Concurrency)
-	for index := 1; index <= g.Concurrency; index++ {
-		go doWorker(jobsCh, wg, g.Exclusion, g.QuietMode, g.Race, g.Tags)
+	// The coverage, test results, overlay and journal are kept in a private directory outside of the source tree
+	workDir, err := utils.GetWorkDir(g.BasePath)
+	if err != nil {
+		return fmt.Errorf("unable to create the work directory. err: %s", err)
 	}
 
-	// Add all the fake code
-	for _, path := range paths {
-		packageName := findPackageName(path)
-		if packageName != UnknownPackage {
-			addFakes(path, packageName)
+	outputDir, err := utils.GetOutputDir(g.BasePath)
+	if err != nil {
+		return fmt.Errorf("unable to create the work directory. err: %s", err)
+	}
+
+	// Record everything that is created so that it can be removed even if this run is aborted
+	records, err := openJournal(g.BasePath)
+	if err != nil {
+		utils.LogAlways("[coverage] unable to create journal; files from an aborted run will not be recoverable. err: %s", err)
+	}
+	defer records.close()
+
+	// The coverage and test results replace those of any previous run
+	records.record(outputDir)
+
+	err = os.RemoveAll(outputDir)
+	if err != nil {
+		utils.LogAlways("[coverage] unable to remove the output of the previous run @ %s. err: %s", outputDir, err)
+	}
+
+	// Reuse the coverage of directories that have not changed
+	var cached *cache
+	if g.CacheDir != "" {
+		cached, err = newCache(g.CacheDir)
+		if err != nil {
+			utils.LogAlways("[coverage] unable to open cache @ %s; all directories will be tested. err: %s", g.CacheDir, err)
 		}
 	}
 
+	// Record how long each directory takes to test
+	var durations *timingsRecorder
+	if g.TimingsSave != "" || g.ShardOutput != "" {
+		durations = newTimingsRecorder(g.BasePath)
+	}
+
+	options := testOptions{
+		basePath:  g.BasePath,
+		outputDir: outputDir,
+		quiet:     g.QuietMode,
+		race:      g.Race,
+		tags:      g.Tags,
+		coverMode: g.CoverMode,
+		coverPkg:  g.CoverPkg,
+		timeout:   g.Timeout,
+	}
+
+	// modules outside of the workspace (if any) must be tested without it
+	options.workFile, options.workspaceModules = utils.FindWorkspace(g.BasePath)
+
+	// Add all the fake code (to an overlay so that the source tree is not modified)
+	fakes, err := newOverlay(workDir)
+	if err != nil {
+		utils.LogAlways("[coverage] unable to create overlay for the fake code; directories without tests will be skipped. err: %s", err)
+	} else {
+		records.record(fakes.dir)
+		defer fakes.remove()
+
+		for _, path := range paths {
+			packageName := findPackageName(path)
+			if packageName != UnknownPackage {
+				addFakes(path, packageName, fakes)
+			}
+		}
+
+		options.overlayFile, err = fakes.save()
+		if err != nil {
+			utils.LogAlways("[coverage] unable to save overlay for the fake code; directories without tests will be skipped. err: %s", err)
+			options.overlayFile = ""
+		}
+	}
+
+	concurrency := g.Concurrency
+	if concurrency < 1 {
+		concurrency = runtime.NumCPU()
+	}
+
+	// create workers
+	wg.Add(concurrency)
+	for index := 1; index <= concurrency; index++ {
+		go doWorker(ctx, jobsCh, wg, g.Exclusion, options, cached, durations)
+	}
+
 	// calculate coverage
 	for _, path := range paths {
 		jobsCh <- path
@@ -127,19 +248,39 @@ func (g *Generator) do(paths []string) {
 	// wait until everything is done
 	wg.Wait()
 
-	// remove the fake code
-	for _, path := range paths {
-		packageName := findPackageName(path)
-		if packageName != UnknownPackage {
-			removeFakes(path)
+	if ctx.Err() != nil {
+		records.close()
+		removeJournaled(g.BasePath)
+		return ctx.Err()
+	}
+
+	cached.report()
+
+	if g.ShardOutput != "" {
+		saveShard(g.ShardOutput, outputDir, g.BasePath, paths, durations.values)
+	}
+
+	if g.TimingsSave != "" {
+		err = saveTimings(g.TimingsSave, durations.values)
+		if err != nil {
+			utils.LogAlways("[coverage] unable to save timings to %s. err: %s", g.TimingsSave, err)
 		}
 	}
+
+	return nil
 }
 
-func doWorker(jobsCh <-chan string, wg *sync.WaitGroup, exclusion *regexp.Regexp, quietMode, race bool, tags string) {
+func doWorker(ctx context.Context, jobsCh <-chan string, wg *sync.WaitGroup, exclusion *regexp.Regexp, options testOptions, cached *cache, durations *timingsRecorder) {
 	defer wg.Done()
 
 	for path := range jobsCh {
-		generateCoverage(path, exclusion, quietMode, race, tags)
+		if ctx.Err() != nil {
+			// drain the remaining jobs
+			continue
+		}
+
+		start := time.Now()
+		generateCoverage(ctx, path, exclusion, options, cached)
+		durations.record(path, time.Since(start))
 	}
 }
@@ -0,0 +1,200 @@
+// Copyright 2017 Corey Scott http://www.sage42.org/
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generator
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/corsc/go-tools/package-coverage/utils"
+)
+
+// journalFilename is the name of the journal in the work directory (see utils.GetWorkDir) so that the journal is
+// outside of the source tree (and is not removed with the output directory)
+const journalFilename = "journal"
+
+// journal records (before they are created) every file and directory the generator creates so that they can be
+// removed after the run, even when the run was aborted.
+type journal struct {
+	file  *os.File
+	mutex sync.Mutex
+}
+
+func getJournalFilename(basePath string) (string, error) {
+	workDir, err := utils.GetWorkDir(basePath)
+	if err != nil {
+		return "", err
+	}
+
+	return workDir + journalFilename, nil
+}
+
+// open the journal for the supplied directory (any entries left by an aborted run are kept).
+// Journals that are not owned by the current user or that other users can write to are refused.
+func openJournal(basePath string) (*journal, error) {
+	filename, err := getJournalFilename(basePath)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(filename, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkJournal(file)
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+
+	return &journal{
+		file: file,
+	}, nil
+}
+
+// record a file that is about to be created (a nil journal records nothing)
+func (j *journal) record(filename string) {
+	if j == nil {
+		return
+	}
+
+	absFilename, err := filepath.Abs(filename)
+	if err != nil {
+		utils.LogAlways("[journal] unable to record %s. err: %s", filename, err)
+		return
+	}
+
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	_, err = fmt.Fprintln(j.file, absFilename)
+	if err != nil {
+		utils.LogAlways("[journal] unable to record %s. err: %s", filename, err)
+	}
+}
+
+func (j *journal) close() {
+	if j == nil {
+		return
+	}
+
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	// the journal is closed early when the run is cancelled
+	if j.file == nil {
+		return
+	}
+
+	err := j.file.Close()
+	if err != nil {
+		utils.LogWhenVerbose("[journal] error while closing journal. err: %s", err)
+	}
+	j.file = nil
+}
+
+// read the (de-duplicated) entries from the journal
+func readJournal(filename string) ([]string, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		_ = file.Close()
+	}()
+
+	err = checkJournal(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var output []string
+	dedupeMap := map[string]struct{}{}
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		entry := scanner.Text()
+		if entry == "" {
+			continue
+		}
+
+		if _, found := dedupeMap[entry]; !found {
+			output = append(output, entry)
+			dedupeMap[entry] = struct{}{}
+		}
+	}
+
+	return output, scanner.Err()
+}
+
+// returns an error when the (open) journal is not owned by the current user or other users can write to it
+func checkJournal(file *os.File) error {
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	return utils.CheckPrivate(file.Name(), info)
+}
+
+// remove exactly the files recorded in the journal for the supplied directory (and then the journal itself).
+// Only entries inside the work directory (i.e. the output directory and the overlay) are removed.
+// Returns false when there is no journal.
+func removeJournaled(basePath string) bool {
+	filename, err := getJournalFilename(basePath)
+	if err != nil {
+		utils.LogAlways("[journal] unable to find journal. err: %s", err)
+		return false
+	}
+	workDir := filepath.Dir(filename)
+
+	entries, err := readJournal(filename)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			utils.LogAlways("[journal] unable to read journal @ %s. err: %s", filename, err)
+		}
+		return false
+	}
+
+	for _, entry := range entries {
+		if !utils.IsWithin(workDir, entry) {
+			utils.LogAlways("[journal] refusing to remove %s as it is outside of %s", entry, workDir)
+			continue
+		}
+
+		utils.LogWhenVerbose("[journal] removing %s", entry)
+
+		// RemoveAll is used as entries can be directories (e.g. the overlay) and might never have been created
+		err = os.RemoveAll(entry)
+		if err != nil {
+			utils.LogAlways("[journal] failed to remove %s. err: %s", entry, err)
+		}
+	}
+
+	err = os.Remove(filename)
+	if err != nil {
+		utils.LogAlways("[journal] failed to remove journal @ %s. err: %s", filename, err)
+	}
+
+	// the work directory is only removed when empty (e.g. not when the run failed to record something)
+	_ = os.Remove(workDir)
+
+	return true
+}
@@ -0,0 +1,113 @@
+// Copyright 2017 Corey Scott http://www.sage42.org/
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generator
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/corsc/go-tools/package-coverage/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJournal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "journal")
+	assert.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+
+	workDir := getTestWorkDir(t, dir)
+
+	created := filepath.Join(workDir, coverageFilename)
+	createdDir := filepath.Join(workDir, "overlay")
+	untouched := filepath.Join(dir, "untouched.go")
+
+	for _, filename := range []string{created, untouched} {
+		assert.NoError(t, ioutil.WriteFile(filename, []byte("foo"), 0600))
+	}
+	assert.NoError(t, os.Mkdir(createdDir, 0700))
+
+	// entries that escape the work directory via a symlink are refused too
+	link := filepath.Join(workDir, "link")
+	assert.NoError(t, os.Symlink(dir, link))
+
+	records, err := openJournal(dir)
+	assert.NoError(t, err)
+	records.record(created)
+	records.record(createdDir)
+	records.record(created)
+	records.record(filepath.Join(workDir, "never-created.cov"))
+	records.record(untouched)
+	records.record(filepath.Join(link, "untouched.go"))
+	records.close()
+
+	filename, err := getJournalFilename(dir)
+	assert.NoError(t, err)
+	assert.False(t, strings.HasPrefix(filename, dir+"/"), "journal should not be in the source tree")
+
+	entries, err := readJournal(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{created, createdDir, filepath.Join(workDir, "never-created.cov"), untouched, filepath.Join(link, "untouched.go")}, entries)
+
+	assert.True(t, removeJournaled(dir))
+	assertFileNotExists(t, created)
+	assertFileNotExists(t, createdDir)
+	assertFileNotExists(t, filename)
+	assertFileExists(t, untouched)
+
+	// journal has been removed
+	assert.False(t, removeJournaled(dir))
+}
+
+func TestJournal_NotPrivate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "journal")
+	assert.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+
+	getTestWorkDir(t, dir)
+
+	filename, err := getJournalFilename(dir)
+	assert.NoError(t, err)
+
+	// journals other users can write to are refused
+	assert.NoError(t, ioutil.WriteFile(filename, []byte(dir+"\n"), 0600))
+	assert.NoError(t, os.Chmod(filename, 0622))
+
+	_, err = openJournal(dir)
+	assert.Error(t, err)
+
+	_, err = readJournal(filename)
+	assert.Error(t, err)
+
+	assert.False(t, removeJournaled(dir))
+	assertFileExists(t, dir)
+}
+
+// returns the work directory of the base path (see utils.GetWorkDir) which is removed after the test
+func getTestWorkDir(t *testing.T, basePath string) string {
+	workDir, err := utils.GetWorkDir(basePath)
+	assert.NoError(t, err)
+	t.Cleanup(func() {
+		_ = os.RemoveAll(workDir)
+	})
+
+	return workDir
+}
@@ -0,0 +1,98 @@
+// Copyright 2017 Corey Scott http://www.sage42.org/
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generator
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+
+	"github.com/corsc/go-tools/package-coverage/utils"
+)
+
+const overlayFilename = "overlay.json"
+
+// overlay injects files into the go build without writing them into the source tree (see "go help build" -overlay)
+type overlay struct {
+	// dir is the temporary directory holding the overlay config and the replacement files
+	dir string
+
+	// Replace maps the path the go command should see to the temporary file containing the contents
+	// (this is the only field included in the config passed to the go command)
+	Replace map[string]string
+
+	mutex sync.Mutex
+}
+
+func newOverlay(workDir string) (*overlay, error) {
+	dir, err := ioutil.TempDir(workDir, "overlay-")
+	if err != nil {
+		return nil, err
+	}
+
+	return &overlay{
+		dir:     dir,
+		Replace: map[string]string{},
+	}, nil
+}
+
+// add a file that the go command will see at target
+func (o *overlay) add(target string, contents string) error {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	// the go command resolves relative paths against the directory of each run
+	absTarget, err := filepath.Abs(target)
+	if err != nil {
+		return err
+	}
+
+	filename := filepath.Join(o.dir, strconv.Itoa(len(o.Replace))+"_"+filepath.Base(target))
+
+	err = ioutil.WriteFile(filename, []byte(contents), 0600)
+	if err != nil {
+		return err
+	}
+
+	o.Replace[absTarget] = filename
+	return nil
+}
+
+// save the overlay config and return its location (for use with "go test -overlay")
+func (o *overlay) save() (string, error) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	contents, err := json.Marshal(o)
+	if err != nil {
+		return "", err
+	}
+
+	filename := filepath.Join(o.dir, overlayFilename)
+	return filename, ioutil.WriteFile(filename, contents, 0600)
+}
+
+// remove the overlay config and all the replacement files
+func (o *overlay) remove() {
+	utils.LogWhenVerbose("[coverage] removing overlay @ %s", o.dir)
+
+	err := os.RemoveAll(o.dir)
+	if err != nil {
+		utils.LogWhenVerbose("[coverage] error while removing overlay @ %s, err: %s", o.dir, err)
+	}
+}
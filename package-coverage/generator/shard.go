@@ -0,0 +1,274 @@
+// Copyright 2017 Corey Scott http://www.sage42.org/
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generator
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/corsc/go-tools/package-coverage/utils"
+)
+
+// shardTimingsFilename is the file the durations of the directories tested by a shard are saved to (in the shard
+// artifacts)
+const shardTimingsFilename = "timings.json"
+
+// timings are the durations (in seconds) it took to test each directory (relative to the base path)
+type timings map[string]float64
+
+// load the timings from the supplied file (missing or broken files result in no timings)
+func loadTimings(filename string) timings {
+	output := timings{}
+
+	contents, err := ioutil.ReadFile(filename)
+	if err != nil {
+		utils.LogWhenVerbose("[shard] unable to read timings file %s. err: %s", filename, err)
+		return output
+	}
+
+	err = json.Unmarshal(contents, &output)
+	if err != nil {
+		utils.LogAlways("[shard] ignoring timings file %s. err: %s", filename, err)
+		return timings{}
+	}
+
+	return output
+}
+
+func saveTimings(filename string, values timings) error {
+	contents, err := json.MarshalIndent(values, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(filename, append(contents, '\n'), 0644)
+}
+
+// timingsRecorder records how long each directory took to test (a nil recorder records nothing)
+type timingsRecorder struct {
+	basePath string
+	values   timings
+	mutex    sync.Mutex
+}
+
+func newTimingsRecorder(basePath string) *timingsRecorder {
+	return &timingsRecorder{
+		basePath: basePath,
+		values:   timings{},
+	}
+}
+
+func (r *timingsRecorder) record(path string, elapsed time.Duration) {
+	if r == nil {
+		return
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.values[getRelativeDir(r.basePath, path)] = elapsed.Seconds()
+}
+
+// returns the directory relative to the base path (using forward slashes so that timings are portable)
+func getRelativeDir(basePath string, path string) string {
+	absBasePath, err := filepath.Abs(basePath)
+	if err != nil {
+		return filepath.ToSlash(path)
+	}
+
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return filepath.ToSlash(path)
+	}
+
+	relPath, err := filepath.Rel(absBasePath, absPath)
+	if err != nil {
+		return filepath.ToSlash(path)
+	}
+
+	return filepath.ToSlash(relPath)
+}
+
+// returns the directories (in their original order) to be tested by the shard (index is 1 based).
+// The directories are split so that the total (historical) duration of each shard is as even as possible by assigning
+// the slowest remaining directory to the shard with the lowest total.  Directories without a timing are assumed to
+// take the average time (or 1 second when there are no timings).  Every shard calculates the same split as long as it
+// is supplied the same directories and timings.
+func selectShard(paths []string, basePath string, index int, count int, known timings) []string {
+	weights := make(map[string]float64, len(paths))
+
+	total := 0.0
+	found := 0
+	for _, path := range paths {
+		if duration, ok := known[getRelativeDir(basePath, path)]; ok {
+			weights[path] = duration
+			total += duration
+			found++
+		}
+	}
+
+	average := 1.0
+	if found > 0 {
+		average = total / float64(found)
+	}
+
+	for _, path := range paths {
+		if _, ok := weights[path]; !ok {
+			weights[path] = average
+		}
+	}
+
+	sorted := append([]string{}, paths...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if weights[sorted[i]] != weights[sorted[j]] {
+			return weights[sorted[i]] > weights[sorted[j]]
+		}
+		return sorted[i] < sorted[j]
+	})
+
+	totals := make([]float64, count)
+	selected := map[string]struct{}{}
+
+	for _, path := range sorted {
+		shard := 0
+		for candidate := 1; candidate < count; candidate++ {
+			if totals[candidate] < totals[shard] {
+				shard = candidate
+			}
+		}
+
+		totals[shard] += weights[path]
+		if shard == index-1 {
+			selected[path] = struct{}{}
+		}
+	}
+
+	var output []string
+	for _, path := range paths {
+		if _, ok := selected[path]; ok {
+			output = append(output, path)
+		}
+	}
+
+	utils.LogAlways("[shard] shard %d/%d is testing %d of %d directories (estimated %.1fs)", index, count, len(output), len(paths), totals[index-1])
+	return output
+}
+
+// copy the coverage and test results of the directories (and the timings) from the output directory into the shard
+// artifacts directory (mirroring the directories relative to the base path) so that they can be merged by DoShardMerge
+func saveShard(shardDir string, outputDir string, basePath string, paths []string, values timings) {
+	for _, path := range paths {
+		dir := filepath.Join(shardDir, filepath.FromSlash(getRelativeDir(basePath, path)))
+		outputPath := utils.GetOutputPath(outputDir, basePath, path)
+
+		err := os.MkdirAll(dir, 0755)
+		if err != nil {
+			utils.LogAlways("[shard] unable to create shard artifacts directory %s. err: %s", dir, err)
+			continue
+		}
+
+		for _, filename := range cachedFilenames {
+			err = copyFile(filepath.Join(outputPath, filename), filepath.Join(dir, filename))
+			if err != nil && !os.IsNotExist(err) {
+				utils.LogAlways("[shard] unable to save %s%s to the shard artifacts. err: %s", path, filename, err)
+			}
+		}
+	}
+
+	err := saveTimings(filepath.Join(shardDir, shardTimingsFilename), values)
+	if err != nil {
+		utils.LogAlways("[shard] unable to save the shard timings. err: %s", err)
+	}
+}
+
+// copy the coverage and test results from the shard artifacts directories into the output directory (see
+// utils.GetOutputDir) mirroring the directories they were generated for (replacing the output of any previous run and recording the output directory
+// in the journal so that it is cleaned up).
+// Returns the combined timings and the number of files merged; files that cannot be copied are logged and skipped.
+func mergeShards(outputDir string, artifactDirs []string, records *journal) (timings, int) {
+	combined := timings{}
+	merged := 0
+
+	records.record(outputDir)
+
+	err := os.RemoveAll(outputDir)
+	if err != nil {
+		utils.LogAlways("[shard] unable to remove the output of the previous run @ %s. err: %s", outputDir, err)
+	}
+
+	for _, artifactDir := range artifactDirs {
+		utils.LogWhenVerbose("[shard] merging shard artifacts from %s", artifactDir)
+
+		for dir, duration := range loadTimings(filepath.Join(artifactDir, shardTimingsFilename)) {
+			combined[dir] = duration
+		}
+
+		err = filepath.Walk(artifactDir, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				utils.LogAlways("[shard] unable to read %s. err: %s", path, err)
+				return nil
+			}
+
+			if info.IsDir() || !isCachedFilename(info.Name()) {
+				return nil
+			}
+
+			relPath, err := filepath.Rel(artifactDir, path)
+			if err != nil {
+				utils.LogAlways("[shard] unable to merge %s. err: %s", path, err)
+				return nil
+			}
+
+			destination := filepath.Join(outputDir, relPath)
+			if _, err := os.Stat(destination); err == nil {
+				utils.LogAlways("[shard] replacing existing %s with the file from %s", destination, artifactDir)
+			}
+
+			err = os.MkdirAll(filepath.Dir(destination), 0755)
+			if err != nil {
+				utils.LogAlways("[shard] unable to create directory for %s. err: %s", destination, err)
+				return nil
+			}
+
+			err = copyFile(path, destination)
+			if err != nil {
+				utils.LogAlways("[shard] unable to merge %s. err: %s", path, err)
+				return nil
+			}
+
+			merged++
+			return nil
+		})
+		if err != nil {
+			utils.LogAlways("[shard] unable to merge shard artifacts from %s. err: %s", artifactDir, err)
+		}
+	}
+
+	return combined, merged
+}
+
+func isCachedFilename(name string) bool {
+	for _, filename := range cachedFilenames {
+		if name == filename {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,111 @@
+// Copyright 2017 Corey Scott http://www.sage42.org/
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generator
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/corsc/go-tools/package-coverage/config"
+	"github.com/corsc/go-tools/package-coverage/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelectShard(t *testing.T) {
+	paths := []string{"/base/a/", "/base/b/", "/base/c/", "/base/d/", "/base/e/"}
+
+	// without timings the directories are split evenly
+	var all []string
+	for index := 1; index <= 2; index++ {
+		all = append(all, selectShard(paths, "/base/", index, 2, timings{})...)
+	}
+	assert.ElementsMatch(t, paths, all)
+	assert.Equal(t, []string{"/base/a/", "/base/c/", "/base/e/"}, selectShard(paths, "/base/", 1, 2, timings{}))
+
+	// with timings the slowest directories are spread across the shards (e has no timing and gets the average: 3s)
+	known := timings{"a": 10, "b": 1, "c": 1, "d": 0}
+	assert.Equal(t, []string{"/base/a/"}, selectShard(paths, "/base/", 1, 2, known))
+	assert.Equal(t, []string{"/base/b/", "/base/c/", "/base/d/", "/base/e/"}, selectShard(paths, "/base/", 2, 2, known))
+
+	// more shards than directories
+	assert.Empty(t, selectShard(paths[:1], "/base/", 2, 2, timings{}))
+}
+
+func TestSaveAndMergeShards(t *testing.T) {
+	dir, err := ioutil.TempDir("", "shard")
+	assert.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+
+	source := filepath.Join(dir, "source")
+	destination := filepath.Join(dir, "destination")
+	artifacts := filepath.Join(dir, "artifacts")
+
+	// the destination directories are created when missing
+	assert.NoError(t, os.MkdirAll(filepath.Join(source, "fu", "bar"), 0755))
+	assert.NoError(t, os.MkdirAll(destination, 0755))
+
+	sourceOutputDir := getTestWorkDir(t, source) + "output/"
+	destinationOutputDir := getTestWorkDir(t, destination) + "output/"
+
+	sourceOutput := utils.GetOutputPath(sourceOutputDir, source, filepath.Join(source, "fu", "bar"))
+	assert.NoError(t, os.MkdirAll(sourceOutput, 0700))
+
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(sourceOutput, coverageFilename), []byte("mode: set\n"), 0600))
+
+	saveShard(artifacts, sourceOutputDir, source, []string{filepath.Join(source, "fu", "bar") + "/", filepath.Join(source, "fu") + "/"}, timings{"fu/bar": 1.5})
+
+	records, err := openJournal(destination)
+	assert.NoError(t, err)
+
+	combined, merged := mergeShards(destinationOutputDir, []string{artifacts}, records)
+	records.close()
+
+	assert.Equal(t, timings{"fu/bar": 1.5}, combined)
+	assert.Equal(t, 1, merged)
+
+	// the files are merged into the output directory (rather than the source tree)
+	contents, err := ioutil.ReadFile(filepath.Join(destinationOutputDir, "fu", "bar", coverageFilename))
+	assert.NoError(t, err)
+	assert.Equal(t, "mode: set\n", string(contents))
+	assertFileNotExists(t, filepath.Join(destination, "fu", "bar", coverageFilename))
+
+	// the merged files are removed by the clean up
+	assert.True(t, removeJournaled(destination))
+	assertFileNotExists(t, destinationOutputDir)
+}
+
+func TestDoShardMerge_NoArtifacts(t *testing.T) {
+	dir, err := ioutil.TempDir("", "shard")
+	assert.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+
+	// the journal of the failed merge is outside of the directory
+	getTestWorkDir(t, dir)
+
+	// no matching directories
+	err = DoShardMerge(&config.Config{ShardMerge: filepath.Join(dir, "shard-*")}, dir)
+	assert.Error(t, err)
+
+	// matching directories without any coverage or test results
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "shard-1", "fu"), 0755))
+	err = DoShardMerge(&config.Config{ShardMerge: filepath.Join(dir, "shard-*")}, dir)
+	assert.Error(t, err)
+}
@@ -15,10 +15,13 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"os"
+	"os/signal"
 	"regexp"
+	"syscall"
 
 	"github.com/corsc/go-tools/package-coverage/config"
 	"github.com/corsc/go-tools/package-coverage/generator"
@@ -26,44 +29,180 @@ import (
 	"github.com/corsc/go-tools/package-coverage/utils"
 )
 
-func main() {
-	defer func() {
-		if r := recover(); r != nil {
-			fmt.Printf("Error: %s\n", r)
-		}
-	}()
+// exit code used when any tests failed
+const exitTestsFailed = 2
 
+func main() {
 	// get config and environment
 	cfg := config.GetConfig()
-	startDir := utils.GetCurrentDir()
 	path := getPath()
+	setDefaultPrefix(cfg, path)
 
 	// build exclusions regex
 	var exclusions *regexp.Regexp
 	if cfg.IgnorePaths != "" {
-		exclusions = regexp.MustCompile(cfg.IgnorePaths)
+		var err error
+
+		exclusions, err = regexp.Compile(cfg.IgnorePaths)
+		if err != nil {
+			fmt.Printf("Error: invalid -i regex: %s\n", err)
+			os.Exit(-1)
+		}
+	}
+
+	// remove the files left behind by an aborted run
+	if generator.DoRecover(cfg, path) {
+		return
+	}
+
+	// stop the tests and clean up when interrupted (at any point of the run)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go handleSignals(ctx, cancel)
+
+	// combine the coverage calculated by the shards
+	err := generator.DoShardMerge(cfg, path)
+	if err != nil {
+		fmt.Printf("Error: %s\n", err)
+		os.Exit(-1)
 	}
 
 	// calculate coverage
-	generator.Calculate(cfg, path, exclusions)
+	err = generator.Calculate(ctx, cfg, path, exclusions)
+	if err != nil {
+		stopWhenInterrupted(ctx, cfg, path, exclusions)
+		fmt.Printf("Error: %s\n", err)
+		os.Exit(-1)
+	}
 
-	// switch back to start dir
-	err := os.Chdir(startDir)
+	// load the coverage (once, including any external coverage profiles) for all the outputs
+	report, err := parser.DoLoad(ctx, cfg, path, exclusions)
 	if err != nil {
-		panic(err)
+		stopWhenInterrupted(ctx, cfg, path, exclusions)
+		fmt.Printf("Error: %s\n", err)
+		os.Exit(-1)
 	}
 
+	// check the coverage against the minimum (regardless of whether it is printed)
+	coverageOk := parser.DoCheck(cfg, report)
+
 	// output coverage to StdOut
-	coverageOk := parser.DoPrint(cfg, path, exclusions)
+	parser.DoPrint(cfg, report)
+
+	// output the test results to StdOut
+	testsOk := parser.DoTests(cfg, report)
+
+	// the remaining outputs are still attempted when an output fails
+	outputsOk := true
+
+	// output as JSON
+	outputsOk = checkOutput("Unable to output the coverage as JSON", parser.DoJSON(cfg, report)) && outputsOk
+
+	// output as Cobertura XML
+	outputsOk = checkOutput("Unable to output the coverage as Cobertura XML", parser.DoCobertura(cfg, report)) && outputsOk
+
+	// output as LCOV
+	outputsOk = checkOutput("Unable to output the coverage as LCOV", parser.DoLCOV(cfg, report)) && outputsOk
+
+	// output the test results as JUnit XML
+	outputsOk = checkOutput("Unable to output the test results as JUnit XML", parser.DoJUnit(cfg, report)) && outputsOk
+
+	// output as Markdown
+	outputsOk = checkOutput("Unable to output the coverage as Markdown", parser.DoMarkdown(cfg, report)) && outputsOk
+
+	// output as SVG badges
+	outputsOk = checkOutput("Unable to output the coverage badges", parser.DoBadges(cfg, report)) && outputsOk
+
+	// output as an SVG treemap
+	outputsOk = checkOutput("Unable to output the coverage treemap", parser.DoTreemap(cfg, report)) && outputsOk
+
+	// output as HTML
+	outputsOk = checkOutput("Unable to output the coverage as HTML", parser.DoHTML(cfg, report)) && outputsOk
+
+	stopWhenInterrupted(ctx, cfg, path, exclusions)
+
+	// compare to and/or save the baseline
+	baselineOk, err := parser.DoBaseline(cfg, report)
+	outputsOk = checkOutput("Unable to compare or save the baseline", err) && outputsOk
 
-	// output to Slack
-	parser.DoSlack(cfg, path, exclusions)
+	// output the coverage of the changed lines
+	diffOk, err := parser.DoDiff(cfg, report)
+	outputsOk = checkOutput("Unable to calculate the coverage of the changed lines", err) && outputsOk
+
+	stopWhenInterrupted(ctx, cfg, path, exclusions)
+
+	// send to the webhook (Slack, Teams, etc)
+	parser.DoNotify(ctx, cfg, report)
+
+	stopWhenInterrupted(ctx, cfg, path, exclusions)
 
 	// clean up
 	generator.DoClean(cfg, path, exclusions)
 
-	// signal success or not
-	if !coverageOk {
+	// signal success or not (failed tests are signalled separately to insufficient coverage)
+	if !testsOk {
+		os.Exit(exitTestsFailed)
+	}
+
+	if !outputsOk || !coverageOk || !baselineOk || !diffOk {
+		os.Exit(-1)
+	}
+}
+
+// cancel the context when SIGINT or SIGTERM is received (a second signal stops the run immediately)
+func handleSignals(ctx context.Context, cancel context.CancelFunc) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	select {
+	case sig := <-signals:
+		utils.LogAlways("received %s; stopping and cleaning up", sig)
+		cancel()
+
+	case <-ctx.Done():
+	}
+}
+
+// when the run was interrupted, remove the files it created (regardless of -d) and exit
+func stopWhenInterrupted(ctx context.Context, cfg *config.Config, path string, exclusions *regexp.Regexp) {
+	if ctx.Err() == nil {
+		return
+	}
+
+	generator.Clean(path, exclusions, cfg.SingleDir)
+	os.Exit(-1)
+}
+
+// log the supplied error of an output (when there is one) and return false when there was an error
+func checkOutput(msg string, err error) bool {
+	if err == nil {
+		return true
+	}
+
+	utils.LogAlways("%s. err: %s", msg, err)
+	return false
+}
+
+// derive the prefix from the module path when there is a single module (with multiple modules, the console output is
+// grouped by module and the prefix of each module is derived from its module path)
+func setDefaultPrefix(cfg *config.Config, path string) {
+	if cfg.Prefix != "" {
+		return
+	}
+
+	modules, err := utils.FindAllModules(path)
+	if err != nil {
+		utils.LogWhenVerbose("Unable to find modules. err: %s", err)
+	}
+
+	if len(modules) == 1 {
+		cfg.Prefix = utils.GetModulePrefix(modules[0])
+		utils.LogWhenVerbose("Using prefix '%s' from module '%s'", cfg.Prefix, modules[0].Path)
+	}
+
+	if cfg.Depth > 0 && len(cfg.Prefix) == 0 && len(modules) <= 1 {
+		println("You must specify a prefix when using -depth")
 		os.Exit(-1)
 	}
 }
@@ -0,0 +1,147 @@
+// Copyright 2017 Corey Scott http://www.sage42.org/
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package notifier sends a summary of the coverage to chat services and other webhooks
+package notifier
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	// DefaultGoodCoverage is the coverage above which a package is considered good
+	DefaultGoodCoverage = 70
+
+	// DefaultWarningCoverage is the coverage above which a package is considered a warning (and below which is danger)
+	DefaultWarningCoverage = 50
+
+	// DefaultTimeout is the default timeout of each request
+	DefaultTimeout = 10 * time.Second
+
+	// DefaultRetries is the default number of times a failed request is retried
+	DefaultRetries = 3
+
+	// DefaultBackoff is the default delay before the first retry (doubled for each subsequent retry)
+	DefaultBackoff = 500 * time.Millisecond
+)
+
+// Notifier sends the coverage report somewhere
+type Notifier interface {
+	Notify(ctx context.Context, report *Report) error
+}
+
+// Level is how healthy the coverage of a package is
+type Level string
+
+const (
+	// LevelGood means the coverage is above the good threshold
+	LevelGood Level = "good"
+
+	// LevelWarning means the coverage is above the warning threshold (but not above the good threshold)
+	LevelWarning Level = "warning"
+
+	// LevelDanger means the coverage is not above the warning threshold
+	LevelDanger Level = "danger"
+)
+
+// Thresholds are the coverage percentages used to calculate the Level of each package
+type Thresholds struct {
+	// Good is the coverage above which a package is good
+	Good float64
+
+	// Warning is the coverage above which a package is a warning
+	Warning float64
+}
+
+// DefaultThresholds returns the thresholds used when none are supplied (good > 70%, warning > 50%)
+func DefaultThresholds() Thresholds {
+	return Thresholds{
+		Good:    DefaultGoodCoverage,
+		Warning: DefaultWarningCoverage,
+	}
+}
+
+// Level returns the level of the supplied coverage percentage
+func (t Thresholds) Level(coverage float64) Level {
+	if coverage > t.Good {
+		return LevelGood
+	} else if coverage > t.Warning {
+		return LevelWarning
+	}
+	return LevelDanger
+}
+
+// Report is the coverage summary that is sent
+type Report struct {
+	// Title is the heading of the message
+	Title string `json:"title"`
+
+	// Packages is the coverage of each package (in output order)
+	Packages []Package `json:"packages"`
+}
+
+// Package is the coverage of a single package (including its children)
+type Package struct {
+	// Name is the package name with the prefix removed
+	Name string `json:"name"`
+
+	// Coverage is the percentage of statements covered
+	Coverage float64 `json:"coverage"`
+
+	// Covered is the number of statements covered
+	Covered int `json:"covered"`
+
+	// Statements is the number of statements
+	Statements int `json:"statements"`
+
+	// Level is calculated from the Coverage and the Thresholds (always danger when TimedOut)
+	Level Level `json:"level"`
+
+	// TimedOut is set when the tests of the package did not finish within the timeout (the coverage is incomplete)
+	TimedOut bool `json:"timedOut,omitempty"`
+}
+
+// Options controls how the notifications are sent
+type Options struct {
+	// Timeout is the timeout of each request (0 means DefaultTimeout)
+	Timeout time.Duration
+
+	// Retries is the number of times a failed request is retried (0 means never)
+	Retries int
+
+	// Backoff is the delay before the first retry, doubled for each subsequent retry (0 means DefaultBackoff)
+	Backoff time.Duration
+}
+
+// DefaultOptions returns the options used when none are supplied
+func DefaultOptions() Options {
+	return Options{
+		Timeout: DefaultTimeout,
+		Retries: DefaultRetries,
+		Backoff: DefaultBackoff,
+	}
+}
+
+// split the packages into batches of at most size packages (always returns at least 1 batch)
+func batchPackages(packages []Package, size int) [][]Package {
+	var output [][]Package
+
+	for len(packages) > size {
+		output = append(output, packages[:size])
+		packages = packages[size:]
+	}
+
+	return append(output, packages)
+}
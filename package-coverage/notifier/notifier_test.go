@@ -0,0 +1,149 @@
+// Copyright 2017 Corey Scott http://www.sage42.org/
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestThresholdsLevel(t *testing.T) {
+	thresholds := Thresholds{Good: 80, Warning: 60}
+
+	assert.Equal(t, LevelGood, thresholds.Level(80.01))
+	assert.Equal(t, LevelWarning, thresholds.Level(80))
+	assert.Equal(t, LevelWarning, thresholds.Level(60.01))
+	assert.Equal(t, LevelDanger, thresholds.Level(60))
+	assert.Equal(t, LevelDanger, DefaultThresholds().Level(50))
+}
+
+func TestSlackNotify(t *testing.T) {
+	server, payloads := newRecordingServer()
+	defer server.Close()
+
+	report := &Report{Title: "Test Coverage"}
+	for x := 0; x < slackPackagesPerMessage+1; x++ {
+		report.Packages = append(report.Packages, Package{Name: "pkg" + strconv.Itoa(x) + "/", Coverage: 75, Statements: 10, Level: LevelGood})
+	}
+	report.Packages[0].Name = `"quoted"/`
+	report.Packages[0].Level = LevelDanger
+
+	err := NewSlack(server.URL, "#coverage", Options{}).Notify(context.Background(), report)
+	assert.Nil(t, err)
+
+	messages := payloads()
+	assert.Len(t, messages, 2)
+
+	message := &slackMessage{}
+	assert.Nil(t, json.Unmarshal(messages[0], message))
+	assert.Equal(t, "#coverage", message.Channel)
+	assert.Equal(t, "header", message.Blocks[0].Type)
+	assert.Len(t, message.Blocks, slackPackagesPerMessage+1)
+	assert.Equal(t, ":red_circle: `\"quoted\"/` *75.00%* (10 statements)", message.Blocks[1].Text.Text)
+
+	assert.Nil(t, json.Unmarshal(messages[1], message))
+	assert.Len(t, message.Blocks, 2)
+}
+
+func TestTeamsNotify(t *testing.T) {
+	server, payloads := newRecordingServer()
+	defer server.Close()
+
+	report := &Report{
+		Title: "Test Coverage",
+		Packages: []Package{
+			{Name: "fu/", Coverage: 55.5, Statements: 20, Level: LevelWarning},
+		},
+	}
+
+	err := NewTeams(server.URL, Options{}).Notify(context.Background(), report)
+	assert.Nil(t, err)
+
+	messages := payloads()
+	assert.Len(t, messages, 1)
+
+	message := &teamsMessage{}
+	assert.Nil(t, json.Unmarshal(messages[0], message))
+	assert.Equal(t, teamsCardContentType, message.Attachments[0].ContentType)
+
+	card := message.Attachments[0].Content
+	assert.Equal(t, "AdaptiveCard", card.Type)
+	assert.Len(t, card.Body, 2)
+	assert.Equal(t, "fu/", card.Body[1].Columns[0].Items[0].Text)
+	assert.Equal(t, "55.50% (20 statements)", card.Body[1].Columns[1].Items[0].Text)
+	assert.Equal(t, "Warning", card.Body[1].Columns[1].Items[0].Color)
+}
+
+func TestTemplateNotify(t *testing.T) {
+	server, payloads := newRecordingServer()
+	defer server.Close()
+
+	report := &Report{
+		Title: `Coverage "main"`,
+		Packages: []Package{
+			{Name: "fu/", Coverage: 90, Covered: 9, Statements: 10, Level: LevelGood},
+		},
+	}
+
+	target, err := NewTemplate(server.URL, `{"text": {{ json .Title }}, "worst": {{ json (index .Packages 0).Level }}, "count": {{ len .Packages }}}`, Options{})
+	assert.Nil(t, err)
+
+	err = target.Notify(context.Background(), report)
+	assert.Nil(t, err)
+
+	messages := payloads()
+	assert.Len(t, messages, 1)
+	assert.JSONEq(t, `{"text": "Coverage \"main\"", "worst": "good", "count": 1}`, string(messages[0]))
+}
+
+func TestTemplateInvalid(t *testing.T) {
+	_, err := NewTemplate("http://localhost", `{{ .Title `, Options{})
+	assert.Error(t, err)
+
+	target, err := NewTemplate("http://localhost", `{"text": {{ .Title }}}`, Options{})
+	assert.Nil(t, err)
+
+	_, err = target.render(&Report{Title: "not quoted"})
+	assert.Error(t, err)
+}
+
+// returns a server recording the body of each request and a function returning the recorded bodies
+func newRecordingServer() (*httptest.Server, func() [][]byte) {
+	mutex := &sync.Mutex{}
+	var payloads [][]byte
+
+	server := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		body, _ := ioutil.ReadAll(req.Body)
+
+		mutex.Lock()
+		payloads = append(payloads, body)
+		mutex.Unlock()
+	}))
+
+	return server, func() [][]byte {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		return payloads
+	}
+}
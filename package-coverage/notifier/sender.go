@@ -0,0 +1,119 @@
+// Copyright 2017 Corey Scott http://www.sage42.org/
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/corsc/go-commons/iocloser"
+	"github.com/corsc/go-tools/package-coverage/utils"
+)
+
+// limit on how much of an error response is included in the error
+const maxErrorBody = 512
+
+// limit on how much of the rest of a response is read (and discarded) so that the connection can be reused; larger
+// responses are closed instead
+const maxDrainBody = 64 * 1024
+
+// sender posts JSON payloads to a webhook, retrying (with exponential backoff) when the request fails or the
+// server responds with 429 or 5xx
+type sender struct {
+	client  *http.Client
+	retries int
+	backoff time.Duration
+}
+
+func newSender(options Options) *sender {
+	if options.Timeout <= 0 {
+		options.Timeout = DefaultTimeout
+	}
+
+	if options.Backoff <= 0 {
+		options.Backoff = DefaultBackoff
+	}
+
+	if options.Retries < 0 {
+		options.Retries = 0
+	}
+
+	return &sender{
+		client: &http.Client{
+			Timeout: options.Timeout,
+		},
+		retries: options.Retries,
+		backoff: options.Backoff,
+	}
+}
+
+// post the payload to the url
+func (s *sender) post(ctx context.Context, url string, payload []byte) error {
+	backoff := s.backoff
+
+	for attempt := 0; ; attempt++ {
+		retry, err := s.postOnce(ctx, url, payload)
+		if err == nil {
+			return nil
+		}
+
+		if !retry || attempt >= s.retries {
+			return err
+		}
+
+		utils.LogWhenVerbose("[notifier] attempt %d failed; retrying in %s. err: %s", attempt+1, backoff, err)
+
+		select {
+		case <-time.After(backoff):
+			backoff *= 2
+
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// post the payload once; returns true when a failure is worth retrying
+func (s *sender) postOnce(ctx context.Context, url string, payload []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		// no point retrying once the caller has given up
+		return ctx.Err() == nil, err
+	}
+	defer func() {
+		_, _ = io.CopyN(ioutil.Discard, resp.Body, maxDrainBody)
+		iocloser.Close(resp.Body)
+	}()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+
+	body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+
+	retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retry, fmt.Errorf("unexpected response code %d; body: %s", resp.StatusCode, body)
+}
@@ -0,0 +1,118 @@
+// Copyright 2017 Corey Scott http://www.sage42.org/
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifier
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSenderPost(t *testing.T) {
+	scenarios := []struct {
+		desc          string
+		responses     []int
+		expectErr     bool
+		expectedCalls int32
+	}{
+		{
+			desc:          "happy path",
+			responses:     []int{http.StatusOK},
+			expectErr:     false,
+			expectedCalls: 1,
+		},
+		{
+			desc:          "retry server errors",
+			responses:     []int{http.StatusInternalServerError, http.StatusTooManyRequests, http.StatusNoContent},
+			expectErr:     false,
+			expectedCalls: 3,
+		},
+		{
+			desc:          "give up after the retries",
+			responses:     []int{http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway, http.StatusOK},
+			expectErr:     true,
+			expectedCalls: 3,
+		},
+		{
+			desc:          "client errors are not retried",
+			responses:     []int{http.StatusBadRequest, http.StatusOK},
+			expectErr:     true,
+			expectedCalls: 1,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		calls := int32(0)
+		server := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+			call := atomic.AddInt32(&calls, 1)
+
+			body, _ := ioutil.ReadAll(req.Body)
+			assert.Equal(t, "application/json", req.Header.Get("Content-Type"), scenario.desc)
+			assert.Equal(t, `{"a":1}`, string(body), scenario.desc)
+
+			resp.WriteHeader(scenario.responses[call-1])
+		}))
+
+		s := newSender(Options{Retries: 2, Backoff: time.Millisecond})
+		err := s.post(context.Background(), server.URL, []byte(`{"a":1}`))
+		server.Close()
+
+		assert.Equal(t, scenario.expectErr, err != nil, scenario.desc)
+		assert.Equal(t, scenario.expectedCalls, atomic.LoadInt32(&calls), scenario.desc)
+	}
+}
+
+func TestSenderPostTimeout(t *testing.T) {
+	done := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		select {
+		case <-done:
+		case <-req.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(done)
+
+	s := newSender(Options{Timeout: 10 * time.Millisecond, Retries: 1, Backoff: time.Millisecond})
+
+	start := time.Now()
+	err := s.post(context.Background(), server.URL, []byte(`{}`))
+	assert.Error(t, err)
+	assert.True(t, time.Since(start) < 5*time.Second)
+}
+
+func TestSenderPostLargeErrorBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		resp.WriteHeader(http.StatusBadRequest)
+		_, _ = resp.Write([]byte(strings.Repeat("x", 10*maxDrainBody)))
+	}))
+	defer server.Close()
+
+	s := newSender(Options{Timeout: time.Second, Retries: 0, Backoff: time.Millisecond})
+
+	// only the start of the body is included in the error
+	err := s.post(context.Background(), server.URL, []byte(`{}`))
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "unexpected response code 400; body: "+strings.Repeat("x", maxErrorBody))
+		assert.NotContains(t, err.Error(), strings.Repeat("x", maxErrorBody+1))
+	}
+}
@@ -0,0 +1,123 @@
+// Copyright 2017 Corey Scott http://www.sage42.org/
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+const (
+	slackUsername = "Test Coverage Bot"
+
+	// Slack allows at most 50 blocks per message (1 is used by the header)
+	slackPackagesPerMessage = 45
+)
+
+var slackEmoji = map[Level]string{
+	LevelGood:    ":large_green_circle:",
+	LevelWarning: ":large_orange_circle:",
+	LevelDanger:  ":red_circle:",
+}
+
+// Slack sends the report to a Slack incoming webhook as Block Kit messages (1 section per package; large reports are
+// split across multiple messages)
+type Slack struct {
+	webhook         string
+	channelOverride string
+	sender          *sender
+}
+
+// NewSlack returns a Notifier for the supplied Slack incoming webhook.  channelOverride is optional.
+func NewSlack(webhook string, channelOverride string, options Options) *Slack {
+	return &Slack{
+		webhook:         webhook,
+		channelOverride: channelOverride,
+		sender:          newSender(options),
+	}
+}
+
+type slackMessage struct {
+	Username string       `json:"username"`
+	Channel  string       `json:"channel,omitempty"`
+	Text     string       `json:"text"`
+	Blocks   []slackBlock `json:"blocks"`
+}
+
+type slackBlock struct {
+	Type string     `json:"type"`
+	Text *slackText `json:"text,omitempty"`
+}
+
+type slackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// Notify implements Notifier
+func (s *Slack) Notify(ctx context.Context, report *Report) error {
+	for _, message := range s.buildMessages(report) {
+		payload, err := json.Marshal(message)
+		if err != nil {
+			return err
+		}
+
+		err = s.sender.post(ctx, s.webhook, payload)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *Slack) buildMessages(report *Report) []*slackMessage {
+	var output []*slackMessage
+
+	for _, packages := range batchPackages(report.Packages, slackPackagesPerMessage) {
+		message := &slackMessage{
+			Username: slackUsername,
+			Channel:  s.channelOverride,
+			// fallback for notifications
+			Text: report.Title,
+			Blocks: []slackBlock{
+				{
+					Type: "header",
+					Text: &slackText{Type: "plain_text", Text: report.Title},
+				},
+			},
+		}
+
+		for _, pkg := range packages {
+			text := fmt.Sprintf("%s `%s` *%3.2f%%* (%d statements)", slackEmoji[pkg.Level], pkg.Name, pkg.Coverage, pkg.Statements)
+			if pkg.TimedOut {
+				text = fmt.Sprintf("%s `%s` *timed out*", slackEmoji[pkg.Level], pkg.Name)
+			}
+
+			message.Blocks = append(message.Blocks, slackBlock{
+				Type: "section",
+				Text: &slackText{
+					Type: "mrkdwn",
+					Text: text,
+				},
+			})
+		}
+
+		output = append(output, message)
+	}
+
+	return output
+}
@@ -0,0 +1,150 @@
+// Copyright 2017 Corey Scott http://www.sage42.org/
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+const (
+	teamsCardContentType = "application/vnd.microsoft.card.adaptive"
+	teamsCardSchema      = "http://adaptivecards.io/schemas/adaptive-card.json"
+	teamsCardVersion     = "1.4"
+
+	// keeps each card well below the Teams message size limit
+	teamsPackagesPerMessage = 50
+)
+
+var teamsColors = map[Level]string{
+	LevelGood:    "Good",
+	LevelWarning: "Warning",
+	LevelDanger:  "Attention",
+}
+
+// Teams sends the report to a Microsoft Teams incoming webhook (or workflow) as Adaptive Cards (1 row per package;
+// large reports are split across multiple messages)
+type Teams struct {
+	webhook string
+	sender  *sender
+}
+
+// NewTeams returns a Notifier for the supplied Microsoft Teams webhook
+func NewTeams(webhook string, options Options) *Teams {
+	return &Teams{
+		webhook: webhook,
+		sender:  newSender(options),
+	}
+}
+
+type teamsMessage struct {
+	Type        string            `json:"type"`
+	Attachments []teamsAttachment `json:"attachments"`
+}
+
+type teamsAttachment struct {
+	ContentType string     `json:"contentType"`
+	Content     *teamsCard `json:"content"`
+}
+
+type teamsCard struct {
+	Schema  string         `json:"$schema"`
+	Type    string         `json:"type"`
+	Version string         `json:"version"`
+	Body    []teamsElement `json:"body"`
+}
+
+// teamsElement is a (subset of a) TextBlock, ColumnSet or Column
+type teamsElement struct {
+	Type    string         `json:"type"`
+	Text    string         `json:"text,omitempty"`
+	Size    string         `json:"size,omitempty"`
+	Weight  string         `json:"weight,omitempty"`
+	Color   string         `json:"color,omitempty"`
+	Wrap    bool           `json:"wrap,omitempty"`
+	Width   string         `json:"width,omitempty"`
+	Columns []teamsElement `json:"columns,omitempty"`
+	Items   []teamsElement `json:"items,omitempty"`
+}
+
+// Notify implements Notifier
+func (t *Teams) Notify(ctx context.Context, report *Report) error {
+	for _, message := range t.buildMessages(report) {
+		payload, err := json.Marshal(message)
+		if err != nil {
+			return err
+		}
+
+		err = t.sender.post(ctx, t.webhook, payload)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (t *Teams) buildMessages(report *Report) []*teamsMessage {
+	var output []*teamsMessage
+
+	for _, packages := range batchPackages(report.Packages, teamsPackagesPerMessage) {
+		card := &teamsCard{
+			Schema:  teamsCardSchema,
+			Type:    "AdaptiveCard",
+			Version: teamsCardVersion,
+			Body: []teamsElement{
+				{Type: "TextBlock", Text: report.Title, Size: "Large", Weight: "Bolder", Wrap: true},
+			},
+		}
+
+		for _, pkg := range packages {
+			text := fmt.Sprintf("%3.2f%% (%d statements)", pkg.Coverage, pkg.Statements)
+			if pkg.TimedOut {
+				text = "timed out"
+			}
+
+			card.Body = append(card.Body, teamsElement{
+				Type: "ColumnSet",
+				Columns: []teamsElement{
+					{
+						Type:  "Column",
+						Width: "stretch",
+						Items: []teamsElement{{Type: "TextBlock", Text: pkg.Name, Wrap: true}},
+					},
+					{
+						Type:  "Column",
+						Width: "auto",
+						Items: []teamsElement{{
+							Type:   "TextBlock",
+							Text:   text,
+							Color:  teamsColors[pkg.Level],
+							Weight: "Bolder",
+						}},
+					},
+				},
+			})
+		}
+
+		output = append(output, &teamsMessage{
+			Type: "message",
+			Attachments: []teamsAttachment{
+				{ContentType: teamsCardContentType, Content: card},
+			},
+		})
+	}
+
+	return output
+}
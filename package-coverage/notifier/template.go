@@ -0,0 +1,78 @@
+// Copyright 2017 Corey Scott http://www.sage42.org/
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"text/template"
+)
+
+// Template sends the report to a generic webhook.  The JSON body is rendered from a user-supplied Go template
+// (see text/template) executed with the Report; use the "json" function to encode values, e.g.
+//
+//	{"text": {{ json .Title }}, "packages": {{ json .Packages }}}
+type Template struct {
+	webhook  string
+	template *template.Template
+	sender   *sender
+}
+
+// NewTemplate returns a Notifier for the supplied webhook using the supplied template text
+func NewTemplate(webhook string, text string, options Options) (*Template, error) {
+	tmpl, err := template.New("webhook").Funcs(template.FuncMap{"json": toJSON}).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Template{
+		webhook:  webhook,
+		template: tmpl,
+		sender:   newSender(options),
+	}, nil
+}
+
+// Notify implements Notifier
+func (t *Template) Notify(ctx context.Context, report *Report) error {
+	payload, err := t.render(report)
+	if err != nil {
+		return err
+	}
+
+	return t.sender.post(ctx, t.webhook, payload)
+}
+
+func (t *Template) render(report *Report) ([]byte, error) {
+	buffer := &bytes.Buffer{}
+
+	err := t.template.Execute(buffer, report)
+	if err != nil {
+		return nil, err
+	}
+
+	if !json.Valid(buffer.Bytes()) {
+		return nil, errors.New("webhook template did not render valid JSON: " + buffer.String())
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// template function encoding any value as JSON
+func toJSON(value interface{}) (string, error) {
+	output, err := json.Marshal(value)
+	return string(output), err
+}
@@ -0,0 +1,159 @@
+// Copyright 2017 Corey Scott http://www.sage42.org/
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/corsc/go-tools/package-coverage/utils"
+)
+
+const (
+	attributionHeaderTemplate      = "| %6s | %6s | %6s | %6s | %-98s |\n"
+	attributionLineTemplate        = "| %6.2f | %6.2f | %6.2f | %6d | %-98s |\n"
+	attributionContributorTemplate = "| %6.2f | %6s | %6s | %6s | %-98s |\n"
+)
+
+// attribution is the coverage of a package split by the tests that covered it
+type attribution struct {
+	statementCoverage
+
+	// own is the number of statements covered by the tests in the package's directory
+	own int
+
+	// external is the number of statements covered only by the tests in other directories (e.g. integration tests)
+	external int
+
+	// contributors is the number of statements covered by the tests in each directory
+	contributors map[string]int
+}
+
+// PrintAttribution will print the coverage of each package in the report split into the statements covered by the
+// package's own tests and the statements covered only by the tests of other directories (along with which directories,
+// or external profiles, contributed).
+// This is only useful when the coverage was calculated with -coverpkg and requires the report to be loaded with
+// Attribution set (see LoadOptions).
+func PrintAttribution(writer io.Writer, report *Report, prefix string, depth int) {
+	if !report.merged.attribution {
+		utils.LogAlways("[attribution] the coverage was loaded without attribution")
+		return
+	}
+
+	byPkg := calculateAttribution(report.merged, report.resolver.resolve)
+	printAttribution(writer, byPkg, report.getBaseDir(), newPrefixer(prefix, report.Modules), depth)
+}
+
+// calculate the attribution of each package (keyed by package) from the sources that covered each block of the profile
+func calculateAttribution(merged *profile, resolve func(pkg, file string) string) map[string]*attribution {
+	output := map[string]*attribution{}
+
+	for index, thisBlock := range merged.blocks {
+		// blocks without statements cannot be covered (see getBlocks)
+		if thisBlock.statements == 0 {
+			continue
+		}
+
+		pkgAttribution, found := output[thisBlock.pkg]
+		if !found {
+			pkgAttribution = &attribution{contributors: map[string]int{}}
+			output[thisBlock.pkg] = pkgAttribution
+		}
+
+		pkgAttribution.statements += thisBlock.statements
+
+		sources := merged.coveredBy[index]
+		if len(sources) == 0 {
+			continue
+		}
+
+		pkgAttribution.covered += thisBlock.statements
+
+		own := false
+		ownDir := filepath.Dir(resolve(thisBlock.pkg, thisBlock.file)) + "/"
+
+		for _, source := range sources {
+			pkgAttribution.contributors[source] += thisBlock.statements
+			own = own || source == ownDir
+		}
+
+		if own {
+			pkgAttribution.own += thisBlock.statements
+		} else {
+			pkgAttribution.external += thisBlock.statements
+		}
+	}
+
+	return output
+}
+
+func printAttribution(writer io.Writer, byPkg map[string]*attribution, baseDir string, prefix *prefixer, depth int) {
+	pkgs := make([]string, 0, len(byPkg))
+	for pkg := range byPkg {
+		pkgs = append(pkgs, pkg)
+	}
+	sort.Strings(pkgs)
+
+	_, _ = fmt.Fprint(writer, "Coverage by own tests (Own%) and only by the tests of other directories (Ext%)\n")
+
+	addLine(writer)
+	_, _ = fmt.Fprintf(writer, attributionHeaderTemplate, "Cov%", "Own%", "Ext%", "Stmts", "Package / Tests in")
+	addLine(writer)
+
+	for _, pkg := range pkgs {
+		pkgFormatted := prefix.trim(pkg)
+		if !withinDepth(pkgFormatted, depth) {
+			continue
+		}
+
+		pkgAttribution := byPkg[pkg]
+		statements := float64(pkgAttribution.statements)
+
+		_, _ = fmt.Fprintf(writer, attributionLineTemplate, pkgAttribution.percentage(),
+			getPercentage(statements, float64(pkgAttribution.own)),
+			getPercentage(statements, float64(pkgAttribution.external)),
+			pkgAttribution.statements, pkgFormatted)
+
+		dirs := make([]string, 0, len(pkgAttribution.contributors))
+		for dir := range pkgAttribution.contributors {
+			dirs = append(dirs, dir)
+		}
+		sort.Strings(dirs)
+
+		for _, dir := range dirs {
+			_, _ = fmt.Fprintf(writer, attributionContributorTemplate,
+				getPercentage(statements, float64(pkgAttribution.contributors[dir])), "", "", "",
+				"    <- "+getRelativePath(baseDir, dir))
+		}
+	}
+	addLine(writer)
+}
+
+// returns the supplied path relative to the base directory (directories keep their trailing slash)
+func getRelativePath(baseDir string, path string) string {
+	relative, err := filepath.Rel(baseDir, path)
+	if err != nil {
+		return path
+	}
+
+	if strings.HasSuffix(path, "/") {
+		return relative + "/"
+	}
+
+	return relative
+}
@@ -0,0 +1,97 @@
+// Copyright 2017 Corey Scott http://www.sage42.org/
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCalculateAttribution(t *testing.T) {
+	// the tests in /src/fu/ cover fu and bar; the (integration) tests in /src/tests/ cover bar only
+	merged := newProfile(nil)
+	merged.attribution = true
+
+	profiles := map[string]string{
+		"/src/fu/": `mode: set
+github.com/corsc/fu/a.go:3.24,4.12 1 1
+github.com/corsc/fu/a.go:4.12,6.3 1 0
+github.com/corsc/fu/bar/b.go:1.1,2.2 2 1
+github.com/corsc/fu/bar/b.go:3.1,4.2 2 0
+`,
+		"/src/tests/": `mode: set
+github.com/corsc/fu/a.go:3.24,4.12 1 0
+github.com/corsc/fu/a.go:4.12,6.3 1 0
+github.com/corsc/fu/bar/b.go:1.1,2.2 2 1
+github.com/corsc/fu/bar/b.go:3.1,4.2 2 1
+github.com/corsc/fu/bar/b.go:5.1,6.2 4 0
+`,
+	}
+
+	for source, contents := range profiles {
+		assert.NoError(t, merged.read(strings.NewReader(contents), source, false))
+	}
+
+	resolve := func(pkg, file string) string {
+		return filepath.Join("/src", pkg[len("github.com/corsc/"):], file)
+	}
+
+	result := calculateAttribution(merged, resolve)
+
+	expected := map[string]*attribution{
+		"github.com/corsc/fu/": {
+			statementCoverage: statementCoverage{statements: 2, covered: 1},
+			own:               1,
+			contributors:      map[string]int{"/src/fu/": 1},
+		},
+		"github.com/corsc/fu/bar/": {
+			statementCoverage: statementCoverage{statements: 8, covered: 4},
+			external:          4,
+			contributors:      map[string]int{"/src/fu/": 2, "/src/tests/": 4},
+		},
+	}
+	assert.Equal(t, expected, result)
+
+	buffer := &bytes.Buffer{}
+	printAttribution(buffer, result, "/src", newPrefixer("github.com/corsc/", nil), 0)
+	assert.Contains(t, buffer.String(), "|  50.00 |   0.00 |  50.00 |      8 | fu/bar/ ")
+	assert.Contains(t, buffer.String(), "|  50.00 |        |        |        |     <- tests/ ")
+	assert.Contains(t, buffer.String(), "|  25.00 |        |        |        |     <- fu/ ")
+}
+
+func TestPrintAttribution_NoExclusions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "attribution")
+	assert.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+
+	writeTestOutput(t, dir, "", "profile.cov", "mode: set\ngithub.com/corsc/fu/a.go:3.24,4.12 1 1\n")
+
+	report, err := Load(context.Background(), LoadOptions{BasePath: dir, Attribution: true})
+	assert.NoError(t, err)
+
+	buffer := &bytes.Buffer{}
+	PrintAttribution(buffer, report, "github.com/corsc/", 0)
+	assert.Contains(t, buffer.String(), "fu/")
+	assert.Contains(t, buffer.String(), "<- ./")
+}
@@ -0,0 +1,162 @@
+// Copyright 2017 Corey Scott http://www.sage42.org/
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/corsc/go-tools/package-coverage/notifier"
+)
+
+const (
+	badgeLabel         = "coverage"
+	badgeTotalFilename = "coverage.svg"
+	badgeHeight        = 20
+
+	// horizontal padding of each half of the badge
+	badgePadding = 5
+)
+
+// flat shields-style badge; parameters are total width, label, value, label width, value width, color, label center
+// and value center
+const badgeTemplate = `<svg xmlns="http://www.w3.org/2000/svg" width="%[1]d" height="20" role="img" aria-label="%[2]s: %[3]s">
+<title>%[2]s: %[3]s</title>
+<linearGradient id="s" x2="0" y2="100%%"><stop offset="0" stop-color="#bbb" stop-opacity=".1"/><stop offset="1" stop-opacity=".1"/></linearGradient>
+<clipPath id="r"><rect width="%[1]d" height="20" rx="3" fill="#fff"/></clipPath>
+<g clip-path="url(#r)"><rect width="%[4]d" height="20" fill="#555"/><rect x="%[4]d" width="%[5]d" height="20" fill="%[6]s"/><rect width="%[1]d" height="20" fill="url(#s)"/></g>
+<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">
+<text x="%[7]d" y="15" fill="#010101" fill-opacity=".3">%[2]s</text><text x="%[7]d" y="14">%[2]s</text>
+<text x="%[8]d" y="15" fill="#010101" fill-opacity=".3">%[3]s</text><text x="%[8]d" y="14">%[3]s</text>
+</g>
+</svg>
+`
+
+var badgeColors = map[notifier.Level]string{
+	notifier.LevelGood:    "#4c1",
+	notifier.LevelWarning: "#dfb317",
+	notifier.LevelDanger:  "#e05d44",
+}
+
+// BadgeCoverage will write an SVG badge for the total coverage of the report (coverage.svg) and for each package
+// within the depth (coverage-<package>.svg) into the output directory.
+// The badges are colored using the thresholds.
+func BadgeCoverage(outputDir string, report *Report, thresholds notifier.Thresholds, prefix string, depth int) error {
+	return writeBadges(outputDir, report.pkgs, report.coverageData, thresholds, newPrefixer(prefix, report.Modules), depth)
+}
+
+func writeBadges(outputDir string, pkgs []string, coverageData coverageByPackage, thresholds notifier.Thresholds, prefix *prefixer, depth int) error {
+	err := os.MkdirAll(outputDir, 0755)
+	if err != nil {
+		return fmt.Errorf("error creating badge output directory '%s': %w", outputDir, err)
+	}
+
+	total, _, _ := getTotalValues(pkgs, coverageData)
+
+	err = writeBadgeFile(filepath.Join(outputDir, badgeTotalFilename), badgeLabel, fmt.Sprintf("%.1f%%", total),
+		badgeColors[thresholds.Level(total)])
+	if err != nil {
+		return err
+	}
+
+	// packages with the same name (e.g. in different modules) are named after the full package instead
+	used := map[string]struct{}{}
+
+	forEachPackage(pkgs, prefix, depth, func(pkg string, pkgFormatted string) {
+		if err != nil {
+			return
+		}
+
+		badgeFilename := getBadgeFilename(pkg, pkgFormatted)
+		if _, found := used[badgeFilename]; found {
+			badgeFilename = getBadgeFilename(pkg, pkg)
+		}
+		used[badgeFilename] = struct{}{}
+
+		filename := filepath.Join(outputDir, badgeFilename)
+
+		cover := coverageData[pkg]
+		if cover.timedOut {
+			err = writeBadgeFile(filename, badgeLabel, "timed out", badgeColors[notifier.LevelDanger])
+			return
+		}
+
+		covered, _, _ := getSummaryValues(cover)
+		err = writeBadgeFile(filename, badgeLabel, fmt.Sprintf("%.1f%%", covered), badgeColors[thresholds.Level(covered)])
+	})
+
+	return err
+}
+
+func writeBadgeFile(filename string, label string, value string, color string) (err error) {
+	output, err := createOutput(filename)
+	if err != nil {
+		return err
+	}
+	defer closeOutput(filename, output, &err)
+
+	writer := newErrWriter(output)
+	writeBadge(writer, label, value, color)
+	if writer.err != nil {
+		return fmt.Errorf("error writing badge '%s': %w", filename, writer.err)
+	}
+
+	return nil
+}
+
+func writeBadge(writer io.Writer, label string, value string, color string) {
+	labelWidth := getBadgeTextWidth(label) + 2*badgePadding
+	valueWidth := getBadgeTextWidth(value) + 2*badgePadding
+
+	_, _ = fmt.Fprintf(writer, badgeTemplate, labelWidth+valueWidth, html.EscapeString(label), html.EscapeString(value),
+		labelWidth, valueWidth, color, labelWidth/2, labelWidth+valueWidth/2)
+}
+
+// estimate the width (in pixels) of the text in 11px Verdana
+func getBadgeTextWidth(text string) int {
+	width := 0.0
+
+	for _, char := range text {
+		switch {
+		case strings.ContainsRune(" .,:;!|'()[]/\\", char), char == 'i', char == 'l', char == 'I':
+			width += 4
+
+		case char == '%', char == 'm', char == 'w', char == 'M', char == 'W':
+			width += 11
+
+		default:
+			width += 7
+		}
+	}
+
+	return int(math.Ceil(width))
+}
+
+// returns a filesystem safe badge filename for the supplied package (named after the package without the prefix; see
+// escapeFilename)
+func getBadgeFilename(pkg string, pkgFormatted string) string {
+	name := strings.Trim(pkgFormatted, "/")
+	if name == "" {
+		name = path.Base(strings.Trim(pkg, "/"))
+	}
+
+	return "coverage-" + escapeFilename(name) + ".svg"
+}
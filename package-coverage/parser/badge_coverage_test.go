@@ -0,0 +1,117 @@
+// Copyright 2017 Corey Scott http://www.sage42.org/
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/corsc/go-tools/package-coverage/notifier"
+	"github.com/corsc/go-tools/package-coverage/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteBadges(t *testing.T) {
+	dir, err := ioutil.TempDir("", "badges")
+	assert.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+
+	pkgs, coverageData := getTestCoverage(t, `mode: set
+github.com/corsc/fu/a.go:3.24,4.12 1 1
+github.com/corsc/fu/a.go:4.12,6.3 1 0
+github.com/corsc/fu/bar/b.go:1.1,2.2 2 2
+github.com/corsc/fu/bar/baz/c.go:1.1,2.2 4 0
+`)
+
+	outputDir := filepath.Join(dir, "badges")
+	assert.NoError(t, writeBadges(outputDir, pkgs, coverageData, notifier.Thresholds{Good: 90, Warning: 35}, newPrefixer("github.com/corsc/fu/", nil), 1))
+
+	files, err := filepath.Glob(filepath.Join(outputDir, "*.svg"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(outputDir, "coverage-bar.svg"),
+		filepath.Join(outputDir, "coverage-fu.svg"),
+		filepath.Join(outputDir, "coverage.svg"),
+	}, files)
+
+	total, err := ioutil.ReadFile(filepath.Join(outputDir, "coverage.svg"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(total), `aria-label="coverage: 37.5%"`)
+	assert.Contains(t, string(total), `fill="#dfb317"`)
+
+	pkg, err := ioutil.ReadFile(filepath.Join(outputDir, "coverage-bar.svg"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(pkg), `aria-label="coverage: 33.3%"`)
+	assert.Contains(t, string(pkg), `fill="#e05d44"`)
+}
+
+func TestWriteBadge(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	writeBadge(buffer, "coverage", "100.0%", "#4c1")
+
+	output := buffer.String()
+	assert.Contains(t, output, `<svg xmlns="http://www.w3.org/2000/svg" width="119" height="20"`)
+	assert.Contains(t, output, `<rect x="66" width="53" height="20" fill="#4c1"/>`)
+	assert.Contains(t, output, `<text x="92" y="14">100.0%</text>`)
+}
+
+func TestWriteBadges_Collisions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "badges")
+	assert.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+
+	pkgs, coverageData := getTestCoverage(t, `mode: set
+github.com/corsc/fu/bar/a.go:1.1,2.2 1 1
+github.com/corsc/fu_bar/b.go:1.1,2.2 1 0
+github.com/corsc/tools/util/c.go:1.1,2.2 1 1
+github.com/other/tools/util/d.go:1.1,2.2 1 0
+`)
+
+	modules := []*utils.Module{
+		{Dir: "/src/corsc/tools/", Path: "github.com/corsc/tools"},
+		{Dir: "/src/other/tools/", Path: "github.com/other/tools"},
+	}
+
+	// the packages in both modules are named "tools/util/" without the prefix
+	outputDir := filepath.Join(dir, "badges")
+	assert.NoError(t, writeBadges(outputDir, pkgs, coverageData, notifier.Thresholds{Good: 90, Warning: 35}, newPrefixer("", modules), 0))
+
+	files, err := filepath.Glob(filepath.Join(outputDir, "*.svg"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(outputDir, "coverage-github.com_corsc_fu-5fbar.svg"),
+		filepath.Join(outputDir, "coverage-github.com_corsc_fu_bar.svg"),
+		filepath.Join(outputDir, "coverage-github.com_other_tools_util.svg"),
+		filepath.Join(outputDir, "coverage-tools_util.svg"),
+		filepath.Join(outputDir, "coverage.svg"),
+	}, files)
+
+	pkg, err := ioutil.ReadFile(filepath.Join(outputDir, "coverage-github.com_other_tools_util.svg"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(pkg), `aria-label="coverage: 0.0%"`)
+}
+
+func TestGetBadgeFilename(t *testing.T) {
+	assert.Equal(t, "coverage-fu_bar.svg", getBadgeFilename("github.com/corsc/fu/bar/", "fu/bar/"))
+	assert.Equal(t, "coverage-fu-5fbar.svg", getBadgeFilename("github.com/corsc/fu_bar/", "fu_bar/"))
+	assert.Equal(t, "coverage-fu.svg", getBadgeFilename("github.com/corsc/fu/", ""))
+}
@@ -0,0 +1,242 @@
+// Copyright 2017 Corey Scott http://www.sage42.org/
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+
+	"github.com/corsc/go-tools/package-coverage/utils"
+)
+
+const baselineTemplate = "| %-11s | %7s | %7s | %7s | %7s | %-80s |\n"
+
+type baselineStatus string
+
+const (
+	statusRegressed   baselineStatus = "regressed"
+	statusImproved    baselineStatus = "improved"
+	statusAppeared    baselineStatus = "appeared"
+	statusDisappeared baselineStatus = "disappeared"
+)
+
+// baseline is a snapshot of the per-package coverage used to detect regressions in later runs
+type baseline struct {
+	Packages map[string]*baselinePackage `json:"packages"`
+}
+
+// baselinePackage is the snapshot of a single package (percentages of the package itself and of its children).
+// The statements are used to skip comparing coverage that had no statements (as 0 statements is 100% covered).
+type baselinePackage struct {
+	Self  float64 `json:"self"`
+	Child float64 `json:"child"`
+
+	SelfStatements  int `json:"selfStatements"`
+	ChildStatements int `json:"childStatements"`
+
+	// TimedOut is set when the tests of the package did not finish within the timeout (the coverage is incomplete)
+	TimedOut bool `json:"timedOut,omitempty"`
+}
+
+// baselineChange is the difference between the baseline and the current coverage of a single package
+type baselineChange struct {
+	pkg      string
+	status   baselineStatus
+	previous *baselinePackage
+	current  *baselinePackage
+}
+
+func buildBaseline(pkgs []string, coverageData coverageByPackage) *baseline {
+	output := &baseline{
+		Packages: make(map[string]*baselinePackage, len(pkgs)),
+	}
+
+	for _, pkg := range pkgs {
+		cover := coverageData[pkg]
+
+		output.Packages[pkg] = &baselinePackage{
+			Self:            getPercentage(float64(cover.selfStatements), float64(cover.selfCovered)),
+			Child:           getPercentage(float64(cover.childStatements), float64(cover.childCovered)),
+			SelfStatements:  cover.selfStatements,
+			ChildStatements: cover.childStatements,
+			TimedOut:        cover.timedOut,
+		}
+	}
+
+	return output
+}
+
+func saveBaseline(filename string, snapshot *baseline) error {
+	contents, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding baseline: %w", err)
+	}
+
+	err = ioutil.WriteFile(filename, append(contents, '\n'), 0644)
+	if err != nil {
+		return fmt.Errorf("error writing baseline file '%s': %w", filename, err)
+	}
+
+	return nil
+}
+
+func loadBaseline(filename string) (*baseline, error) {
+	contents, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("error reading baseline file '%s': %w", filename, err)
+	}
+
+	snapshot := &baseline{}
+
+	err = json.Unmarshal(contents, snapshot)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding baseline file '%s': %w", filename, err)
+	}
+
+	return snapshot, nil
+}
+
+// compare the current coverage to the baseline.
+// Packages are only considered regressed when self or child coverage drops by more than the tolerance.
+// Coverage that had no statements in the baseline is not compared (e.g. the child coverage of a package without
+// sub-packages is 100% and adding a sub-package would otherwise be a regression of every parent).
+// The coverage of packages that timed out is incomplete so it is not compared; timing out is always a regression and
+// finishing after timing out in the baseline is an improvement.
+func compareBaseline(previous, current *baseline, tolerance float64) []*baselineChange {
+	var output []*baselineChange
+
+	// baselines saved before the statements were recorded can only be compared by percentage
+	withStatements := previous.hasStatements()
+	if !withStatements {
+		utils.LogAlways("[baseline] the baseline does not contain the number of statements; save it again to skip comparing coverage without statements")
+	}
+
+	for pkg, currentPkg := range current.Packages {
+		previousPkg, found := previous.Packages[pkg]
+
+		if currentPkg.TimedOut {
+			output = append(output, &baselineChange{pkg: pkg, status: statusRegressed, previous: previousPkg, current: currentPkg})
+			continue
+		}
+
+		if !found {
+			output = append(output, &baselineChange{pkg: pkg, status: statusAppeared, current: currentPkg})
+			continue
+		}
+
+		if previousPkg.TimedOut {
+			output = append(output, &baselineChange{pkg: pkg, status: statusImproved, previous: previousPkg, current: currentPkg})
+			continue
+		}
+
+		selfDelta := currentPkg.Self - previousPkg.Self
+		if withStatements && previousPkg.SelfStatements == 0 {
+			selfDelta = 0
+		}
+
+		childDelta := currentPkg.Child - previousPkg.Child
+		if withStatements && previousPkg.ChildStatements == 0 {
+			childDelta = 0
+		}
+
+		change := &baselineChange{pkg: pkg, previous: previousPkg, current: currentPkg}
+
+		switch {
+		case selfDelta < -tolerance || childDelta < -tolerance:
+			change.status = statusRegressed
+
+		case selfDelta > 0 || childDelta > 0:
+			change.status = statusImproved
+
+		default:
+			continue
+		}
+
+		output = append(output, change)
+	}
+
+	for pkg, previousPkg := range previous.Packages {
+		if _, found := current.Packages[pkg]; !found {
+			output = append(output, &baselineChange{pkg: pkg, status: statusDisappeared, previous: previousPkg})
+		}
+	}
+
+	sort.Slice(output, func(i, j int) bool {
+		return output[i].pkg < output[j].pkg
+	})
+
+	return output
+}
+
+// returns true when any package of the baseline has statements (i.e. the baseline contains the statements)
+func (b *baseline) hasStatements() bool {
+	for _, snapshot := range b.Packages {
+		if snapshot.SelfStatements > 0 || snapshot.ChildStatements > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// print the changes compared to the baseline and return false when any package has regressed
+func printBaselineChanges(writer io.Writer, changes []*baselineChange, tolerance float64, prefix *prefixer) bool {
+	_, _ = fmt.Fprintf(writer, "Coverage compared to baseline (tolerance: %.2f%%)\n", tolerance)
+
+	addLine(writer)
+	_, _ = fmt.Fprintf(writer, baselineTemplate, "Status", "Self", "", "Child", "", "Package")
+	_, _ = fmt.Fprintf(writer, baselineTemplate, "", "Before", "After", "Before", "After", "")
+	addLine(writer)
+
+	baselineOk := true
+	counts := map[baselineStatus]int{}
+
+	for _, change := range changes {
+		counts[change.status]++
+
+		template := baselineTemplate
+		if change.status == statusRegressed {
+			template = errHighlightStart + baselineTemplate + errHighlightEnd
+			baselineOk = false
+		}
+
+		previousSelf, previousChild := formatBaselinePackage(change.previous)
+		currentSelf, currentChild := formatBaselinePackage(change.current)
+
+		pkgFormatted := prefix.trim(change.pkg)
+		_, _ = fmt.Fprintf(writer, template, change.status, previousSelf, currentSelf, previousChild, currentChild, pkgFormatted)
+	}
+	addLine(writer)
+
+	_, _ = fmt.Fprintf(writer, "%d regressed, %d improved, %d appeared, %d disappeared\n",
+		counts[statusRegressed], counts[statusImproved], counts[statusAppeared], counts[statusDisappeared])
+
+	return baselineOk
+}
+
+func formatBaselinePackage(snapshot *baselinePackage) (string, string) {
+	if snapshot == nil {
+		return "-", "-"
+	}
+
+	if snapshot.TimedOut {
+		return "timeout", "timeout"
+	}
+
+	return fmt.Sprintf("%.2f", snapshot.Self), fmt.Sprintf("%.2f", snapshot.Child)
+}
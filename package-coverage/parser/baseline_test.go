@@ -0,0 +1,170 @@
+// Copyright 2017 Corey Scott http://www.sage42.org/
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildBaseline(t *testing.T) {
+	coverageData := coverageByPackage{
+		"fu/": {
+			selfStatements:  4,
+			selfCovered:     1,
+			childStatements: 2,
+			childCovered:    1,
+		},
+		"fu/bar/": {
+			selfStatements: 2,
+			selfCovered:    1,
+		},
+		"fu/baz/": {
+			timedOut: true,
+		},
+	}
+
+	expected := &baseline{
+		Packages: map[string]*baselinePackage{
+			"fu/":     {Self: 25, Child: 50, SelfStatements: 4, ChildStatements: 2},
+			"fu/bar/": {Self: 50, Child: 100, SelfStatements: 2},
+			"fu/baz/": {Self: 100, Child: 100, TimedOut: true},
+		},
+	}
+
+	result := buildBaseline(getSortedPackages(coverageData), coverageData)
+	assert.Equal(t, expected, result)
+}
+
+func TestCompareBaseline(t *testing.T) {
+	previous := &baseline{
+		Packages: map[string]*baselinePackage{
+			"regressed/":   {Self: 80, Child: 100},
+			"tolerated/":   {Self: 80, Child: 100},
+			"improved/":    {Self: 50, Child: 100},
+			"unchanged/":   {Self: 50, Child: 50},
+			"disappeared/": {Self: 10, Child: 20},
+		},
+	}
+	current := &baseline{
+		Packages: map[string]*baselinePackage{
+			"regressed/": {Self: 80, Child: 90},
+			"tolerated/": {Self: 79.5, Child: 100},
+			"improved/":  {Self: 60, Child: 100},
+			"unchanged/": {Self: 50, Child: 50},
+			"appeared/":  {Self: 30, Child: 100},
+		},
+	}
+
+	changes := compareBaseline(previous, current, 1)
+
+	result := map[string]baselineStatus{}
+	for _, change := range changes {
+		result[change.pkg] = change.status
+	}
+
+	expected := map[string]baselineStatus{
+		"regressed/":   statusRegressed,
+		"improved/":    statusImproved,
+		"appeared/":    statusAppeared,
+		"disappeared/": statusDisappeared,
+	}
+	assert.Equal(t, expected, result)
+
+	buffer := &bytes.Buffer{}
+	assert.False(t, printBaselineChanges(buffer, changes, 1, newPrefixer("", nil)))
+	assert.Contains(t, buffer.String(), "1 regressed, 1 improved, 1 appeared, 1 disappeared")
+}
+
+func TestCompareBaseline_NoRegressions(t *testing.T) {
+	snapshot := &baseline{
+		Packages: map[string]*baselinePackage{
+			"fu/": {Self: 80, Child: 100},
+		},
+	}
+
+	changes := compareBaseline(snapshot, snapshot, 0)
+	assert.Empty(t, changes)
+
+	buffer := &bytes.Buffer{}
+	assert.True(t, printBaselineChanges(buffer, changes, 0, newPrefixer("", nil)))
+}
+
+func TestCompareBaseline_NewChild(t *testing.T) {
+	previous := &baseline{
+		Packages: map[string]*baselinePackage{
+			"fu/":     {Self: 80, Child: 100, SelfStatements: 10},
+			"fu/bar/": {Self: 100, Child: 100},
+		},
+	}
+
+	// a new sub-package (with lower coverage) and the first statements of an empty package are not regressions
+	current := &baseline{
+		Packages: map[string]*baselinePackage{
+			"fu/":         {Self: 80, Child: 25, SelfStatements: 10, ChildStatements: 8},
+			"fu/bar/":     {Self: 50, Child: 100, SelfStatements: 4},
+			"fu/bar/baz/": {Self: 0, Child: 100, SelfStatements: 4},
+		},
+	}
+
+	changes := compareBaseline(previous, current, 0)
+
+	assert.Len(t, changes, 1)
+	assert.Equal(t, "fu/bar/baz/", changes[0].pkg)
+	assert.Equal(t, statusAppeared, changes[0].status)
+}
+
+func TestCompareBaseline_TimedOut(t *testing.T) {
+	previous := &baseline{
+		Packages: map[string]*baselinePackage{
+			"timedout/":  {Self: 80, Child: 100, SelfStatements: 10},
+			"finished/":  {Self: 0, Child: 100, TimedOut: true},
+			"still/":     {Self: 0, Child: 100, TimedOut: true},
+			"unchanged/": {Self: 50, Child: 100, SelfStatements: 10},
+		},
+	}
+	current := &baseline{
+		Packages: map[string]*baselinePackage{
+			"timedout/":  {Self: 100, Child: 100, TimedOut: true},
+			"finished/":  {Self: 50, Child: 100, SelfStatements: 10},
+			"still/":     {Self: 0, Child: 100, TimedOut: true},
+			"new/":       {Self: 100, Child: 100, TimedOut: true},
+			"unchanged/": {Self: 50, Child: 100, SelfStatements: 10},
+		},
+	}
+
+	changes := compareBaseline(previous, current, 0)
+
+	result := map[string]baselineStatus{}
+	for _, change := range changes {
+		result[change.pkg] = change.status
+	}
+
+	// packages that timed out are regressions (even when they did not exist or already timed out in the baseline)
+	expected := map[string]baselineStatus{
+		"timedout/": statusRegressed,
+		"finished/": statusImproved,
+		"still/":    statusRegressed,
+		"new/":      statusRegressed,
+	}
+	assert.Equal(t, expected, result)
+
+	buffer := &bytes.Buffer{}
+	assert.False(t, printBaselineChanges(buffer, changes, 0, newPrefixer("", nil)))
+	assert.Contains(t, buffer.String(), "| regressed   |   80.00 | timeout |  100.00 | timeout | timedout/ ")
+	assert.Contains(t, buffer.String(), "3 regressed, 1 improved, 0 appeared, 0 disappeared")
+}
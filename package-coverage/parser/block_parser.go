@@ -0,0 +1,34 @@
+// Copyright 2017 Corey Scott http://www.sage42.org/
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+// block is a single line of a coverage profile (a block of code and the number of times it was executed)
+type block struct {
+	pkg  string
+	file string
+
+	startLine int
+	startCol  int
+	endLine   int
+	endCol    int
+
+	statements int
+	count      int
+}
+
+// filename returns the package qualified filename of the block (as it appears in the coverage profile)
+func (b block) filename() string {
+	return b.pkg + b.file
+}
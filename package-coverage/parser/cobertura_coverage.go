@@ -0,0 +1,211 @@
+// Copyright 2017 Corey Scott http://www.sage42.org/
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+const coberturaDocType = `<!DOCTYPE coverage SYSTEM "http://cobertura.sourceforge.net/xml/coverage-04.dtd">` + "\n"
+
+type coberturaCoverage struct {
+	XMLName         xml.Name            `xml:"coverage"`
+	LineRate        float64             `xml:"line-rate,attr"`
+	BranchRate      float64             `xml:"branch-rate,attr"`
+	LinesCovered    int                 `xml:"lines-covered,attr"`
+	LinesValid      int                 `xml:"lines-valid,attr"`
+	BranchesCovered int                 `xml:"branches-covered,attr"`
+	BranchesValid   int                 `xml:"branches-valid,attr"`
+	Complexity      float64             `xml:"complexity,attr"`
+	Version         string              `xml:"version,attr"`
+	Timestamp       int64               `xml:"timestamp,attr"`
+	Sources         []string            `xml:"sources>source"`
+	Packages        []*coberturaPackage `xml:"packages>package"`
+}
+
+type coberturaPackage struct {
+	Name       string            `xml:"name,attr"`
+	LineRate   float64           `xml:"line-rate,attr"`
+	BranchRate float64           `xml:"branch-rate,attr"`
+	Complexity float64           `xml:"complexity,attr"`
+	Classes    []*coberturaClass `xml:"classes>class"`
+}
+
+type coberturaClass struct {
+	Name       string           `xml:"name,attr"`
+	Filename   string           `xml:"filename,attr"`
+	LineRate   float64          `xml:"line-rate,attr"`
+	BranchRate float64          `xml:"branch-rate,attr"`
+	Complexity float64          `xml:"complexity,attr"`
+	Methods    []struct{}       `xml:"methods>method"`
+	Lines      []*coberturaLine `xml:"lines>line"`
+}
+
+type coberturaLine struct {
+	Number int    `xml:"number,attr"`
+	Hits   int    `xml:"hits,attr"`
+	Branch string `xml:"branch,attr"`
+}
+
+// CoberturaCoverage will output the coverage in the report in the Cobertura XML format.
+// Packages are output by their full name and filenames have the prefix removed.
+func CoberturaCoverage(writer io.Writer, report *Report, prefix string) error {
+	return writeCobertura(writer, buildCobertura(report.blocks, newPrefixer(prefix, report.Modules), time.Now()))
+}
+
+func writeCobertura(writer io.Writer, report *coberturaCoverage) error {
+	_, _ = io.WriteString(writer, xml.Header)
+	_, _ = io.WriteString(writer, coberturaDocType)
+
+	encoder := xml.NewEncoder(writer)
+	encoder.Indent("", "  ")
+
+	err := encoder.Encode(report)
+	if err != nil {
+		return fmt.Errorf("error encoding coverage as Cobertura XML: %w", err)
+	}
+
+	_, _ = io.WriteString(writer, "\n")
+	return nil
+}
+
+func buildCobertura(blocks []block, prefix *prefixer, now time.Time) *coberturaCoverage {
+	report := &coberturaCoverage{
+		Version:   "package-coverage",
+		Timestamp: now.UnixNano() / int64(time.Millisecond),
+		Sources:   []string{"."},
+	}
+
+	linesByFile := getLineHitsByFile(blocks)
+	filesByPkg := map[string][]string{}
+	for _, block := range blocks {
+		filesByPkg[block.pkg] = appendUnique(filesByPkg[block.pkg], block.file)
+	}
+
+	for _, pkg := range getSortedKeys(filesByPkg) {
+		cPkg := &coberturaPackage{
+			Name: strings.TrimSuffix(pkg, "/"),
+		}
+
+		pkgCovered, pkgValid := 0, 0
+
+		files := filesByPkg[pkg]
+		sort.Strings(files)
+
+		for _, file := range files {
+			lineHits := linesByFile[pkg+file]
+
+			class := &coberturaClass{
+				Name:     strings.TrimSuffix(file, ".go"),
+				Filename: prefix.trim(pkg + file),
+				Methods:  []struct{}{},
+			}
+
+			covered := 0
+			for _, line := range getSortedLines(lineHits) {
+				hits := lineHits[line]
+				if hits > 0 {
+					covered++
+				}
+
+				class.Lines = append(class.Lines, &coberturaLine{Number: line, Hits: hits, Branch: "false"})
+			}
+
+			class.LineRate = getRate(covered, len(lineHits))
+			cPkg.Classes = append(cPkg.Classes, class)
+
+			pkgCovered += covered
+			pkgValid += len(lineHits)
+		}
+
+		cPkg.LineRate = getRate(pkgCovered, pkgValid)
+		report.Packages = append(report.Packages, cPkg)
+
+		report.LinesCovered += pkgCovered
+		report.LinesValid += pkgValid
+	}
+
+	report.LineRate = getRate(report.LinesCovered, report.LinesValid)
+
+	return report
+}
+
+// converts blocks into the number of times each line was executed (per file).
+// When blocks overlap the highest count is used.
+func getLineHitsByFile(blocks []block) map[string]map[int]int {
+	output := map[string]map[int]int{}
+
+	for _, block := range blocks {
+		lineHits, ok := output[block.filename()]
+		if !ok {
+			lineHits = map[int]int{}
+			output[block.filename()] = lineHits
+		}
+
+		for line := block.startLine; line <= block.endLine; line++ {
+			if hits, found := lineHits[line]; !found || block.count > hits {
+				lineHits[line] = block.count
+			}
+		}
+	}
+
+	return output
+}
+
+func getSortedLines(lineHits map[int]int) []int {
+	output := make([]int, 0, len(lineHits))
+	for line := range lineHits {
+		output = append(output, line)
+	}
+
+	sort.Ints(output)
+
+	return output
+}
+
+func getSortedKeys(values map[string][]string) []string {
+	output := make([]string, 0, len(values))
+	for key := range values {
+		output = append(output, key)
+	}
+
+	sort.Strings(output)
+
+	return output
+}
+
+func appendUnique(values []string, value string) []string {
+	for _, existing := range values {
+		if existing == value {
+			return values
+		}
+	}
+
+	return append(values, value)
+}
+
+// returns the ratio of covered to total (between 0 and 1); no lines is considered fully covered
+func getRate(covered, total int) float64 {
+	if total <= 0 {
+		return 1
+	}
+
+	return float64(covered) / float64(total)
+}
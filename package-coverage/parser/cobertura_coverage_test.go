@@ -0,0 +1,93 @@
+// Copyright 2017 Corey Scott http://www.sage42.org/
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseBlock(t *testing.T) {
+	in := "github.com/corsc/go-tools/package-coverage/line_parser.go:9.37,11.2 1 3"
+	expected := block{
+		pkg:        "github.com/corsc/go-tools/package-coverage/",
+		file:       "line_parser.go",
+		startLine:  9,
+		startCol:   37,
+		endLine:    11,
+		endCol:     2,
+		statements: 1,
+		count:      3,
+	}
+
+	result := parseTestBlocks(t, in)
+	assert.Equal(t, []block{expected}, result)
+}
+
+func TestParseBlock_InvalidLineSkipped(t *testing.T) {
+	result := parseTestBlocks(t, "github.com/corsc/go-tools/package-coverage/line_parser.go:9.37 1 3")
+	assert.Empty(t, result)
+}
+
+func TestWriteCobertura(t *testing.T) {
+	in := `mode: set
+github.com/corsc/go-tools/package-coverage/main.go:3.10,4.2 1 1
+github.com/corsc/go-tools/package-coverage/main.go:4.2,6.2 2 0
+github.com/corsc/go-tools/package-coverage/parser/parser.go:1.1,2.2 1 1
+`
+	report := buildCobertura(parseTestBlocks(t, in), newPrefixer("github.com/corsc/go-tools/", nil), time.Unix(1, 0))
+
+	buffer := &bytes.Buffer{}
+	assert.NoError(t, writeCobertura(buffer, report))
+
+	expected := `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE coverage SYSTEM "http://cobertura.sourceforge.net/xml/coverage-04.dtd">
+<coverage line-rate="0.6666666666666666" branch-rate="0" lines-covered="4" lines-valid="6" branches-covered="0" branches-valid="0" complexity="0" version="package-coverage" timestamp="1000">
+  <sources>
+    <source>.</source>
+  </sources>
+  <packages>
+    <package name="github.com/corsc/go-tools/package-coverage" line-rate="0.5" branch-rate="0" complexity="0">
+      <classes>
+        <class name="main" filename="package-coverage/main.go" line-rate="0.5" branch-rate="0" complexity="0">
+          <methods></methods>
+          <lines>
+            <line number="3" hits="1" branch="false"></line>
+            <line number="4" hits="1" branch="false"></line>
+            <line number="5" hits="0" branch="false"></line>
+            <line number="6" hits="0" branch="false"></line>
+          </lines>
+        </class>
+      </classes>
+    </package>
+    <package name="github.com/corsc/go-tools/package-coverage/parser" line-rate="1" branch-rate="0" complexity="0">
+      <classes>
+        <class name="parser" filename="package-coverage/parser/parser.go" line-rate="1" branch-rate="0" complexity="0">
+          <methods></methods>
+          <lines>
+            <line number="1" hits="1" branch="false"></line>
+            <line number="2" hits="1" branch="false"></line>
+          </lines>
+        </class>
+      </classes>
+    </package>
+  </packages>
+</coverage>
+`
+	assert.Equal(t, expected, buffer.String())
+}
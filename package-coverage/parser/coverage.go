@@ -15,50 +15,178 @@
 package parser
 
 import (
-	"io/ioutil"
-	"regexp"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
 	"sort"
+	"strings"
 
 	"github.com/corsc/go-tools/package-coverage/utils"
 )
 
-// get coverage using the paths and exclusions supplied
-func getCoverageData(paths []string, exclusionsMatcher *regexp.Regexp) ([]string, coverageByPackage) {
-	var contents string
-	for _, path := range paths {
-		if exclusionsMatcher.FindString(path) != "" {
-			utils.LogWhenVerbose("[print] Printing of coverage for path '%s' skipped due to exclusions regex '%s'",
-				path, exclusionsMatcher.String())
+// find and read all the coverage and test results files of the supplied options
+func readCoverage(ctx context.Context, options LoadOptions) (*profile, []*testResults, error) {
+	outputDir, err := utils.GetOutputDir(options.BasePath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error finding output directory: %w", err)
+	}
+
+	if options.SingleDir {
+		return readCoverageSingle(ctx, outputDir, options)
+	}
+
+	paths, err := findOutputFiles(outputDir, utils.FindAllCoverageFiles)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error finding coverage files: %w", err)
+	}
+
+	merged, err := readProfiles(ctx, outputDir, paths, options)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	results, err := readTestResults(ctx, outputDir, options.BasePath, options.Exclusions)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return merged, results, nil
+}
+
+// read the coverage and test results files from a single directory
+func readCoverageSingle(ctx context.Context, outputDir string, options LoadOptions) (*profile, []*testResults, error) {
+	merged, err := readSingleProfile(ctx, outputDir, options)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	results, err := readTestResultsSingle(ctx, outputDir, options.BasePath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return merged, results, nil
+}
+
+// mark the packages whose tests timed out so that every output can show them as timed out.  The coverage of these
+// packages is usually missing (go test was stopped before writing it) so they are added without any statements.
+func addTimedOut(pkgs []string, coverageData coverageByPackage, results []*testResults) ([]string, coverageByPackage) {
+	added := false
+
+	for _, pkgResults := range results {
+		if !pkgResults.timedOut {
 			continue
 		}
 
-		contents += getFileContents(path)
+		cover, found := coverageData[pkgResults.pkg]
+		if !found {
+			cover = &coverage{}
+			coverageData[pkgResults.pkg] = cover
+			added = true
+		}
+
+		cover.timedOut = true
 	}
 
-	return getCoverageByContents(contents)
+	if added {
+		pkgs = getSortedPackages(coverageData)
+	}
+
+	return pkgs, coverageData
 }
 
-// get coverage from supplied string (used after concatenating all the individual coverage files together
-func getCoverageByContents(contents string) ([]string, coverageByPackage) {
-	coverageData := getCoverageByPackage(contents)
-	pkgs := getSortedPackages(coverageData)
+// find the files (using the supplied finder) in the output directory (see utils.GetOutputDir).
+// There are no files when the coverage has not been calculated.
+func findOutputFiles(outputDir string, finder func(string) ([]string, error)) ([]string, error) {
+	if _, err := os.Stat(outputDir); os.IsNotExist(err) {
+		utils.LogWhenVerbose("[parser] no output found @ %s", outputDir)
+		return nil, nil
+	}
 
-	return pkgs, coverageData
+	return finder(outputDir)
+}
+
+// returns the location of the coverage file for single directory mode
+func singleCoverageFile(outputDir string) string {
+	return outputDir + "profile.cov"
+}
+
+// returns the full path (with trailing slash) of the directory for single directory mode
+func singleDir(path string) string {
+	if path == "./" {
+		return utils.GetCurrentDir()
+	}
+
+	if filepath.IsAbs(path) {
+		return strings.TrimSuffix(path, "/") + "/"
+	}
+
+	return utils.GetCurrentDir() + path + "/"
 }
 
-// will calculate and return the coverage for a package or packages from the supplied coverage file contents
-func getCoverageByPackage(contents string) coverageByPackage {
-	coverageData := parseLines(contents)
-	return coverageData
+// read and merge all the supplied coverage files that are not excluded (and any external profiles)
+func readProfiles(ctx context.Context, outputDir string, paths []string, options LoadOptions) (*profile, error) {
+	exclusionsMatcher := options.Exclusions
+
+	output := newProfile(exclusionsMatcher)
+	output.attribution = options.Attribution
+
+	for _, path := range paths {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		// the profiles are in the output directory; the exclusions (and attribution) use the directory they were
+		// generated for
+		source := utils.GetSourcePath(outputDir, options.BasePath, filepath.Dir(path))
+
+		if exclusionsMatcher != nil && exclusionsMatcher.FindString(source) != "" {
+			utils.LogWhenVerbose("[print] Printing of coverage for path '%s' skipped due to exclusions regex '%s'",
+				source, exclusionsMatcher.String())
+			continue
+		}
+
+		err := output.readFile(path, source, false)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err := readExternalProfiles(ctx, output, options.Profiles, paths)
+	if err != nil {
+		return nil, err
+	}
+
+	return output, nil
 }
 
-func getFileContents(filename string) string {
-	contents, err := ioutil.ReadFile(filename)
+// read and merge the coverage file for single directory mode (and any external profiles)
+func readSingleProfile(ctx context.Context, outputDir string, options LoadOptions) (*profile, error) {
+	filename := singleCoverageFile(outputDir)
+
+	output := newProfile(nil)
+	output.attribution = options.Attribution
+
+	err := output.readFile(filename, singleDir(options.BasePath), false)
+	if err != nil {
+		return nil, err
+	}
+
+	err = readExternalProfiles(ctx, output, options.Profiles, []string{filename})
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
-	return string(contents)
+	return output, nil
+}
+
+// get the coverage (and the sorted packages) of the supplied profile
+func getCoverageByProfile(merged *profile) ([]string, coverageByPackage) {
+	coverageData := merged.getCoverage()
+	pkgs := getSortedPackages(coverageData)
+
+	return pkgs, coverageData
 }
 
 func getSortedPackages(coverageData coverageByPackage) []string {
@@ -73,6 +201,23 @@ func getSortedPackages(coverageData coverageByPackage) []string {
 	return output
 }
 
+// statementCoverage is the number of statements and covered statements of a subset of the blocks (e.g. a file)
+type statementCoverage struct {
+	statements int
+	covered    int
+}
+
+func (s *statementCoverage) add(block block) {
+	s.statements += block.statements
+	if block.count > 0 {
+		s.covered += block.statements
+	}
+}
+
+func (s *statementCoverage) percentage() float64 {
+	return getPercentage(float64(s.statements), float64(s.covered))
+}
+
 func getSummaryValues(cover *coverage) (float64, float64, float64) {
 	stmts := float64(cover.selfStatements + cover.childStatements)
 	stmtsCovered := float64(cover.selfCovered + cover.childCovered)
@@ -0,0 +1,262 @@
+// Copyright 2017 Corey Scott http://www.sage42.org/
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/corsc/go-tools/package-coverage/utils"
+)
+
+// DiffCoverage will calculate and print the coverage of the statements in the report changed since the supplied git
+// ref.  Returns false when the coverage of the changed statements is below the supplied minimum and an error when git
+// diff fails.
+func DiffCoverage(writer io.Writer, report *Report, baseRef string, minCoverage int, prefix string) (bool, error) {
+	changes, err := getChangedLines(report.options.BasePath, baseRef)
+	if err != nil {
+		return false, err
+	}
+
+	byFile := calculateDiffCoverage(report.blocks, changes, report.resolver.resolve)
+	return printDiffCoverage(writer, byFile, baseRef, float64(minCoverage), newPrefixer(prefix, report.Modules)), nil
+}
+
+// stmtExtent is the location of a statement in a source file.
+// The statement ends at its body (e.g. the opening brace of an if statement) as the body is a separate block.
+type stmtExtent struct {
+	startLine int
+	startCol  int
+	endLine   int
+}
+
+// calculate the coverage of the statements on changed lines (keyed by the package qualified filename)
+func calculateDiffCoverage(blocks []block, changes changedLines, resolve func(pkg, file string) string) map[string]*statementCoverage {
+	output := map[string]*statementCoverage{}
+	stmtsByFile := map[string][]*stmtExtent{}
+
+	for _, block := range blocks {
+		filename := resolve(block.pkg, block.file)
+
+		lines, found := changes[filename]
+		if !found || !linesChanged(block.startLine, block.endLine, lines) {
+			continue
+		}
+
+		stmts, found := stmtsByFile[filename]
+		if !found {
+			var err error
+			stmts, err = findStmts(filename)
+			if err != nil {
+				utils.LogWhenVerbose("[diff] unable to find the statements of '%s'; counting whole blocks. err: %s", filename, err)
+			}
+			stmtsByFile[filename] = stmts
+		}
+
+		changed := countChangedStmts(block, stmts, lines)
+		if changed == 0 {
+			continue
+		}
+
+		cover, found := output[block.filename()]
+		if !found {
+			cover = &statementCoverage{}
+			output[block.filename()] = cover
+		}
+
+		cover.statements += changed
+		if block.count > 0 {
+			cover.covered += changed
+		}
+	}
+
+	return output
+}
+
+// returns the number of statements of the block on changed lines.
+// When the statements found in the source do not match the block (e.g. the source has changed since the coverage was
+// calculated), every statement of the block is counted.
+func countChangedStmts(block block, stmts []*stmtExtent, lines map[int]struct{}) int {
+	found := 0
+	changed := 0
+
+	for _, stmt := range stmts {
+		if !blockContains(block, stmt.startLine, stmt.startCol) {
+			continue
+		}
+
+		found++
+		if linesChanged(stmt.startLine, stmt.endLine, lines) {
+			changed++
+		}
+	}
+
+	if found != block.statements {
+		return block.statements
+	}
+
+	return changed
+}
+
+// returns true when the supplied position is inside the block
+func blockContains(block block, line int, col int) bool {
+	if line < block.startLine || (line == block.startLine && col < block.startCol) {
+		return false
+	}
+
+	return line < block.endLine || (line == block.endLine && col < block.endCol)
+}
+
+func linesChanged(startLine int, endLine int, lines map[int]struct{}) bool {
+	for line := startLine; line <= endLine; line++ {
+		if _, found := lines[line]; found {
+			return true
+		}
+	}
+
+	return false
+}
+
+// find the location of the statements (as counted by the coverage profile) in the supplied source file
+func findStmts(filename string) ([]*stmtExtent, error) {
+	fileSet := token.NewFileSet()
+
+	file, err := parser.ParseFile(fileSet, filename, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var output []*stmtExtent
+
+	addStmts := func(stmts []ast.Stmt) {
+		for _, stmt := range stmts {
+			start := fileSet.Position(stmt.Pos())
+
+			output = append(output, &stmtExtent{
+				startLine: start.Line,
+				startCol:  start.Column,
+				endLine:   fileSet.Position(getStmtEnd(stmt)).Line,
+			})
+		}
+	}
+
+	// the coverage profile counts the statements of each statement list
+	ast.Inspect(file, func(node ast.Node) bool {
+		switch typed := node.(type) {
+		case *ast.BlockStmt:
+			addStmts(typed.List)
+
+		case *ast.CaseClause:
+			addStmts(typed.Body)
+
+		case *ast.CommClause:
+			addStmts(typed.Body)
+		}
+
+		return true
+	})
+
+	return output, nil
+}
+
+// returns the end of the statement without its body or any function literals (which are separate blocks)
+func getStmtEnd(stmt ast.Stmt) token.Pos {
+	switch typed := stmt.(type) {
+	case *ast.BlockStmt:
+		return typed.Lbrace
+
+	case *ast.IfStmt:
+		return typed.Body.Lbrace
+
+	case *ast.ForStmt:
+		return typed.Body.Lbrace
+
+	case *ast.RangeStmt:
+		return typed.Body.Lbrace
+
+	case *ast.SwitchStmt:
+		return typed.Body.Lbrace
+
+	case *ast.TypeSwitchStmt:
+		return typed.Body.Lbrace
+
+	case *ast.SelectStmt:
+		return typed.Body.Lbrace
+
+	case *ast.LabeledStmt:
+		return getStmtEnd(typed.Stmt)
+	}
+
+	end := stmt.End()
+	ast.Inspect(stmt, func(node ast.Node) bool {
+		if funcLit, ok := node.(*ast.FuncLit); ok && funcLit.Body.Lbrace < end {
+			end = funcLit.Body.Lbrace
+		}
+		return true
+	})
+
+	return end
+}
+
+func printDiffCoverage(writer io.Writer, byFile map[string]*statementCoverage, baseRef string, minCoverage float64, prefix *prefixer) bool {
+	byPkg := map[string]*statementCoverage{}
+	filesByPkg := map[string][]string{}
+	total := &statementCoverage{}
+
+	for filename, cover := range byFile {
+		pkg := filename[:(strings.LastIndex(filename, "/") + 1)]
+
+		pkgCover, found := byPkg[pkg]
+		if !found {
+			pkgCover = &statementCoverage{}
+			byPkg[pkg] = pkgCover
+		}
+
+		pkgCover.statements += cover.statements
+		pkgCover.covered += cover.covered
+		filesByPkg[pkg] = append(filesByPkg[pkg], filename)
+
+		total.statements += cover.statements
+		total.covered += cover.covered
+	}
+
+	_, _ = fmt.Fprintf(writer, "Coverage of the statements changed since '%s'\n", baseRef)
+
+	addLine(writer)
+	_, _ = fmt.Fprintf(writer, shortHeaderTemplate, "Cov%", "Cov", "Stmts", "Package / File")
+	addLine(writer)
+
+	for _, pkg := range getSortedKeys(filesByPkg) {
+		addLineStatements(writer, prefix.trim(pkg), byPkg[pkg], minCoverage)
+
+		files := filesByPkg[pkg]
+		sort.Strings(files)
+
+		for _, filename := range files {
+			addLineStatements(writer, "    "+strings.TrimPrefix(filename, pkg), byFile[filename], minCoverage)
+		}
+	}
+	addLine(writer)
+
+	diffOk := addLineStatements(writer, "Total", total, minCoverage)
+	addLine(writer)
+
+	return diffOk
+}
@@ -0,0 +1,222 @@
+// Copyright 2017 Corey Scott http://www.sage42.org/
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDiff(t *testing.T) {
+	in := `diff --git a/pkg/a.go b/pkg/a.go
+index 1111111..2222222 100644
+--- a/pkg/a.go
++++ b/pkg/a.go
+@@ -3 +3 @@ func A() {
+-	return 1
++	return 2
+@@ -10,0 +11,2 @@ func B() {
++	x := 1
++	_ = x
+@@ -20,2 +21,0 @@ func C() {
+-	y := 1
+-	_ = y
+diff --git "a/pkg/caf\303\251.go" "b/pkg/caf\303\251.go"
+--- "a/pkg/caf\303\251.go"
++++ "b/pkg/caf\303\251.go"
+@@ -5 +5 @@
++	return 3
+diff --git a/pkg/with space.go b/pkg/with space.go
+--- a/pkg/with space.go	
++++ b/pkg/with space.go	
+@@ -7 +7 @@
++	return 4
+diff --git a/pkg/deleted.go b/pkg/deleted.go
+deleted file mode 100644
+--- a/pkg/deleted.go
++++ /dev/null
+@@ -1,3 +0,0 @@
+-package pkg
+`
+	expected := changedLines{
+		"/repo/pkg/a.go": {
+			3:  {},
+			11: {},
+			12: {},
+		},
+		"/repo/pkg/café.go": {
+			5: {},
+		},
+		"/repo/pkg/with space.go": {
+			7: {},
+		},
+	}
+
+	result := parseDiff(strings.NewReader(in), "/repo")
+	assert.Equal(t, expected, result)
+}
+
+func TestParseHunkHeader(t *testing.T) {
+	scenarios := []struct {
+		desc          string
+		in            string
+		expectedStart int
+		expectedCount int
+	}{
+		{
+			desc:          "single line",
+			in:            "@@ -3 +3 @@",
+			expectedStart: 3,
+			expectedCount: 1,
+		},
+		{
+			desc:          "multiple lines",
+			in:            "@@ -10,0 +11,2 @@ func B() {",
+			expectedStart: 11,
+			expectedCount: 2,
+		},
+		{
+			desc:          "deleted lines",
+			in:            "@@ -20,2 +19,0 @@",
+			expectedStart: 19,
+			expectedCount: 0,
+		},
+		{
+			desc:          "invalid header",
+			in:            "@@ fu bar",
+			expectedStart: 0,
+			expectedCount: 0,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		start, count := parseHunkHeader(scenario.in)
+		assert.Equal(t, scenario.expectedStart, start, scenario.desc)
+		assert.Equal(t, scenario.expectedCount, count, scenario.desc)
+	}
+}
+
+func TestCalculateDiffCoverage(t *testing.T) {
+	in := `mode: set
+github.com/corsc/fu/a.go:3.10,5.2 2 1
+github.com/corsc/fu/a.go:6.2,8.2 3 0
+github.com/corsc/fu/a.go:10.2,12.2 1 0
+github.com/corsc/fu/b.go:1.1,2.2 1 1
+github.com/corsc/fu/bar/c.go:1.1,2.2 4 0
+`
+	changes := changedLines{
+		"/src/github.com/corsc/fu/a.go":     {4: {}, 7: {}},
+		"/src/github.com/corsc/fu/bar/c.go": {2: {}},
+	}
+	resolve := func(pkg, file string) string {
+		return "/src/" + pkg + file
+	}
+
+	result := calculateDiffCoverage(parseTestBlocks(t, in), changes, resolve)
+
+	expected := map[string]*statementCoverage{
+		"github.com/corsc/fu/a.go":     {statements: 5, covered: 2},
+		"github.com/corsc/fu/bar/c.go": {statements: 4, covered: 0},
+	}
+	assert.Equal(t, expected, result)
+
+	buffer := &bytes.Buffer{}
+	assert.False(t, printDiffCoverage(buffer, result, "HEAD", 50, newPrefixer("github.com/corsc/", nil)))
+	assert.Contains(t, buffer.String(), "|  22.22 |      2 |      9 | Total")
+	assert.Contains(t, buffer.String(), "|  40.00 |      2 |      5 | fu/ ")
+	assert.Contains(t, buffer.String(), "|   0.00 |      0 |      4 | fu/bar/ ")
+}
+
+func TestCalculateDiffCoverage_ChangedStatements(t *testing.T) {
+	dir, err := ioutil.TempDir("", "diff")
+	assert.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+
+	source := `package fu
+
+func A(x int) int {
+	y := x + 1
+	z := y * 2
+	if z > 10 {
+		return 10
+	}
+	go func() {
+		_ = z
+	}()
+	return z
+}
+`
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "a.go"), []byte(source), 0600))
+
+	in := `mode: set
+github.com/corsc/fu/a.go:3.19,6.12 3 1
+github.com/corsc/fu/a.go:6.12,8.3 1 0
+github.com/corsc/fu/a.go:9.2,9.12 1 1
+github.com/corsc/fu/a.go:9.12,11.3 1 0
+github.com/corsc/fu/a.go:12.2,12.10 1 1
+`
+	resolve := func(pkg, file string) string {
+		return filepath.Join(dir, file)
+	}
+
+	scenarios := []struct {
+		desc     string
+		lines    map[int]struct{}
+		expected *statementCoverage
+	}{
+		{
+			desc:     "one line of a multi-line block",
+			lines:    map[int]struct{}{5: {}},
+			expected: &statementCoverage{statements: 1, covered: 1},
+		},
+		{
+			desc:     "the if statement and its body",
+			lines:    map[int]struct{}{6: {}, 7: {}},
+			expected: &statementCoverage{statements: 2, covered: 1},
+		},
+		{
+			desc:     "inside a function literal",
+			lines:    map[int]struct{}{10: {}},
+			expected: &statementCoverage{statements: 1, covered: 0},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		changes := changedLines{filepath.Join(dir, "a.go"): scenario.lines}
+
+		result := calculateDiffCoverage(parseTestBlocks(t, in), changes, resolve)
+		assert.Equal(t, scenario.expected, result["github.com/corsc/fu/a.go"], scenario.desc)
+	}
+}
+
+func TestGetChangedLines_Error(t *testing.T) {
+	dir, err := ioutil.TempDir("", "diff")
+	assert.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+
+	// not a git repository
+	_, err = getChangedLines(dir, "HEAD")
+	assert.Error(t, err)
+}
@@ -0,0 +1,34 @@
+// Copyright 2017 Corey Scott http://www.sage42.org/
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"github.com/corsc/go-tools/package-coverage/config"
+	"github.com/corsc/go-tools/package-coverage/notifier"
+)
+
+// DoBadges will write SVG coverage badges (for the total and each package) into the requested directory
+func DoBadges(cfg *config.Config, report *Report) error {
+	if cfg.BadgeOutput == "" {
+		return nil
+	}
+
+	thresholds := notifier.Thresholds{
+		Good:    cfg.GoodCoverage,
+		Warning: cfg.WarningCoverage,
+	}
+
+	return BadgeCoverage(cfg.BadgeOutput, report, thresholds, cfg.Prefix, cfg.Depth)
+}
@@ -0,0 +1,57 @@
+// Copyright 2017 Corey Scott http://www.sage42.org/
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/corsc/go-tools/package-coverage/config"
+)
+
+// DoBaseline will compare the coverage to the previously saved baseline (when requested) and then save the
+// current coverage as the new baseline (when requested).
+// Returns false when any package has regressed by more than the tolerance.
+func DoBaseline(cfg *config.Config, report *Report) (bool, error) {
+	baselineOk := true
+
+	if cfg.Baseline == "" && cfg.BaselineSave == "" {
+		return baselineOk, nil
+	}
+
+	current := buildBaseline(report.pkgs, report.coverageData)
+
+	if cfg.Baseline != "" {
+		previous, err := loadBaseline(cfg.Baseline)
+		if err != nil {
+			return false, err
+		}
+
+		changes := compareBaseline(previous, current, cfg.BaselineTolerance)
+
+		buffer := bytes.Buffer{}
+		baselineOk = printBaselineChanges(&buffer, changes, cfg.BaselineTolerance, newPrefixer(cfg.Prefix, report.Modules))
+		_, _ = fmt.Fprint(getConsole(cfg), buffer.String())
+	}
+
+	if cfg.BaselineSave != "" {
+		err := saveBaseline(cfg.BaselineSave, current)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	return baselineOk, nil
+}
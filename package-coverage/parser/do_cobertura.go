@@ -0,0 +1,34 @@
+// Copyright 2017 Corey Scott http://www.sage42.org/
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"github.com/corsc/go-tools/package-coverage/config"
+)
+
+// DoCobertura will output the coverage as Cobertura XML to the requested file (or StdOut)
+func DoCobertura(cfg *config.Config, report *Report) (err error) {
+	if cfg.CoberturaOutput == "" {
+		return nil
+	}
+
+	output, err := createOutput(cfg.CoberturaOutput)
+	if err != nil {
+		return err
+	}
+	defer closeOutput(cfg.CoberturaOutput, output, &err)
+
+	return CoberturaCoverage(output, report, cfg.Prefix)
+}
@@ -0,0 +1,40 @@
+// Copyright 2017 Corey Scott http://www.sage42.org/
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/corsc/go-tools/package-coverage/config"
+)
+
+// DoDiff will output the coverage of the lines changed since the requested git ref to StdOut (StdErr when StdOut
+// contains machine-readable output)
+func DoDiff(cfg *config.Config, report *Report) (bool, error) {
+	if cfg.DiffBase == "" {
+		return true, nil
+	}
+
+	buffer := bytes.Buffer{}
+
+	diffOk, err := DiffCoverage(&buffer, report, cfg.DiffBase, cfg.DiffMinCoverage, cfg.Prefix)
+	if err != nil {
+		return false, err
+	}
+
+	_, _ = fmt.Fprint(getConsole(cfg), buffer.String())
+	return diffOk, nil
+}
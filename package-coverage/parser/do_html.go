@@ -0,0 +1,28 @@
+// Copyright 2017 Corey Scott http://www.sage42.org/
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"github.com/corsc/go-tools/package-coverage/config"
+)
+
+// DoHTML will write the coverage as a static HTML report into the requested directory
+func DoHTML(cfg *config.Config, report *Report) error {
+	if cfg.HTMLOutput == "" {
+		return nil
+	}
+
+	return HTMLCoverage(cfg.HTMLOutput, report, cfg.MinCoverage, cfg.Prefix, cfg.Depth)
+}
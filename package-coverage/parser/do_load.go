@@ -0,0 +1,61 @@
+// Copyright 2017 Corey Scott http://www.sage42.org/
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"github.com/corsc/go-tools/package-coverage/config"
+)
+
+// DoLoad will load the coverage (and test results) used by all the outputs.
+// Returns nil (and no error) when the coverage is neither calculated nor output (e.g. when only cleaning up).
+func DoLoad(ctx context.Context, cfg *config.Config, path string, exclusions *regexp.Regexp) (*Report, error) {
+	if !cfg.Coverage && !cfg.DoPrint && !hasOutput(cfg) {
+		return nil, nil
+	}
+
+	options := LoadOptions{
+		BasePath:    path,
+		Exclusions:  exclusions,
+		SingleDir:   cfg.SingleDir,
+		Attribution: cfg.DoPrint && cfg.PrintAttribution,
+	}
+
+	// include any external coverage profiles
+	if cfg.MergeProfiles != "" {
+		options.Profiles = strings.Split(cfg.MergeProfiles, ",")
+	}
+
+	return Load(ctx, options)
+}
+
+// returns true when any output other than the console output was requested
+func hasOutput(cfg *config.Config) bool {
+	outputs := []string{
+		cfg.JSONOutput, cfg.CoberturaOutput, cfg.LCOVOutput, cfg.JUnitOutput, cfg.MarkdownOutput, cfg.BadgeOutput,
+		cfg.TreemapOutput, cfg.HTMLOutput, cfg.Baseline, cfg.BaselineSave, cfg.DiffBase, cfg.WebHook,
+	}
+
+	for _, output := range outputs {
+		if output != "" {
+			return true
+		}
+	}
+
+	return false
+}
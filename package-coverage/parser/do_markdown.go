@@ -0,0 +1,35 @@
+// Copyright 2017 Corey Scott http://www.sage42.org/
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"github.com/corsc/go-tools/package-coverage/config"
+)
+
+// DoMarkdown will output the coverage as a Markdown summary (e.g. for pull request comments) to the requested file
+// (or StdOut)
+func DoMarkdown(cfg *config.Config, report *Report) (err error) {
+	if cfg.MarkdownOutput == "" {
+		return nil
+	}
+
+	output, err := createOutput(cfg.MarkdownOutput)
+	if err != nil {
+		return err
+	}
+	defer closeOutput(cfg.MarkdownOutput, output, &err)
+
+	return MarkdownCoverage(output, report, cfg.MarkdownBaseline, cfg.MinCoverage, cfg.Prefix, cfg.Depth)
+}
@@ -0,0 +1,73 @@
+// Copyright 2017 Corey Scott http://www.sage42.org/
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"context"
+	"io/ioutil"
+
+	"github.com/corsc/go-tools/package-coverage/config"
+	"github.com/corsc/go-tools/package-coverage/notifier"
+	"github.com/corsc/go-tools/package-coverage/utils"
+)
+
+// DoNotify will send the coverage to the webhook using the requested notifier (Slack, Teams or a template)
+func DoNotify(ctx context.Context, cfg *config.Config, report *Report) {
+	if cfg.WebHook == "" {
+		return
+	}
+
+	target, err := newNotifier(cfg)
+	if err != nil {
+		utils.LogAlways("Unable to create the %s notifier. err: %s", cfg.Notifier, err)
+		return
+	}
+
+	thresholds := notifier.Thresholds{
+		Good:    cfg.GoodCoverage,
+		Warning: cfg.WarningCoverage,
+	}
+
+	err = NotifyCoverage(ctx, target, report, thresholds, cfg.Prefix, cfg.Depth)
+	if err != nil {
+		utils.LogAlways("Unable to send the coverage to the webhook. err: %s", err)
+	}
+}
+
+func newNotifier(cfg *config.Config) (notifier.Notifier, error) {
+	options := notifier.DefaultOptions()
+	options.Timeout = cfg.WebHookTimeout
+	options.Retries = cfg.WebHookRetries
+
+	switch cfg.Notifier {
+	case "teams":
+		return notifier.NewTeams(cfg.WebHook, options), nil
+
+	case "template":
+		text, err := ioutil.ReadFile(cfg.WebHookTemplate)
+		if err != nil {
+			return nil, err
+		}
+
+		target, err := notifier.NewTemplate(cfg.WebHook, string(text), options)
+		if err != nil {
+			return nil, err
+		}
+		return target, nil
+
+	default:
+		return notifier.NewSlack(cfg.WebHook, cfg.ChannelOverride, options), nil
+	}
+}
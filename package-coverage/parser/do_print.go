@@ -23,26 +23,40 @@ package parser
 import (
 	"bytes"
 	"fmt"
-	"regexp"
 
 	"github.com/corsc/go-tools/package-coverage/config"
 )
 
-// DoPrint will output the coverage to StdOut
-func DoPrint(cfg *config.Config, path string, exclusions *regexp.Regexp) bool {
-	coverageOk := true
-
+// DoPrint will output the coverage in the report (and the other requested console outputs) to StdOut (or StdErr when
+// a machine-readable output is written to StdOut)
+func DoPrint(cfg *config.Config, report *Report) {
 	if !cfg.DoPrint {
-		return coverageOk
+		return
 	}
 
 	buffer := bytes.Buffer{}
-	if cfg.SingleDir {
-		coverageOk = PrintCoverageSingle(&buffer, path, cfg.MinCoverage, cfg.Prefix, cfg.Depth)
-	} else {
-		coverageOk = PrintCoverage(&buffer, path, exclusions, cfg.MinCoverage, cfg.Prefix, cfg.Depth)
+	PrintCoverage(&buffer, report, cfg.MinCoverage, cfg.Prefix, cfg.Depth)
+
+	if cfg.PrintFiles {
+		PrintFileCoverage(&buffer, report, cfg.MinCoverage, cfg.Prefix, cfg.Depth)
+	}
+
+	if cfg.PrintFuncs {
+		PrintFuncCoverage(&buffer, report, cfg.MinCoverage, cfg.Prefix, cfg.Depth)
+	}
+
+	if cfg.PrintAttribution {
+		PrintAttribution(&buffer, report, cfg.Prefix, cfg.Depth)
+	}
+
+	if cfg.Uncovered != "" {
+		onlyBelow := cfg.Uncovered == "below"
+		PrintUncovered(&buffer, report, onlyBelow, cfg.MinCoverage, cfg.UncoveredContext, cfg.Prefix, cfg.Depth)
+	}
+
+	if cfg.HotPaths > 0 {
+		PrintHotPaths(&buffer, report, cfg.HotPaths, cfg.Prefix, cfg.Depth)
 	}
 
-	fmt.Print(buffer.String())
-	return coverageOk
+	_, _ = fmt.Fprint(getConsole(cfg), buffer.String())
 }
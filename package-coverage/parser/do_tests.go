@@ -0,0 +1,41 @@
+// Copyright 2017 Corey Scott http://www.sage42.org/
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/corsc/go-tools/package-coverage/config"
+)
+
+// DoTests will output the results of the tests run while calculating the coverage (and the slowest packages, when
+// requested) to StdOut.
+// Returns false when any tests failed (regardless of whether the results are printed)
+func DoTests(cfg *config.Config, report *Report) bool {
+	if report == nil {
+		return true
+	}
+
+	buffer := bytes.Buffer{}
+	testsOk := PrintTestResults(&buffer, report, cfg.Prefix)
+	PrintSlowest(&buffer, report, cfg.Slowest, cfg.Prefix)
+
+	if cfg.DoPrint {
+		_, _ = fmt.Fprint(getConsole(cfg), buffer.String())
+	}
+
+	return testsOk
+}
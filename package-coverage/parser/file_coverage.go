@@ -0,0 +1,98 @@
+// Copyright 2017 Corey Scott http://www.sage42.org/
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+// PrintFileCoverage will print the coverage of each file in the report, grouped by package
+func PrintFileCoverage(writer io.Writer, report *Report, minCoverage int, prefix string, depth int) {
+	printFileCoverage(writer, getCoverageByFile(report.getIncludedBlocks()), float64(minCoverage), newPrefixer(prefix, report.Modules), depth)
+}
+
+// calculate the coverage of each file (keyed by package and then by filename)
+func getCoverageByFile(blocks []block) map[string]map[string]*statementCoverage {
+	output := map[string]map[string]*statementCoverage{}
+
+	for _, block := range blocks {
+		files, found := output[block.pkg]
+		if !found {
+			files = map[string]*statementCoverage{}
+			output[block.pkg] = files
+		}
+
+		cover, found := files[block.file]
+		if !found {
+			cover = &statementCoverage{}
+			files[block.file] = cover
+		}
+
+		cover.add(block)
+	}
+
+	return output
+}
+
+func printFileCoverage(writer io.Writer, coverageByFile map[string]map[string]*statementCoverage, minCoverage float64, prefix *prefixer, depth int) {
+	pkgs := make([]string, 0, len(coverageByFile))
+	for pkg := range coverageByFile {
+		pkgs = append(pkgs, pkg)
+	}
+	sort.Strings(pkgs)
+
+	addLine(writer)
+	_, _ = fmt.Fprintf(writer, shortHeaderTemplate, "Cov%", "Cov", "Stmts", "Package / File")
+	addLine(writer)
+
+	for _, pkg := range pkgs {
+		pkgFormatted := prefix.trim(pkg)
+		if !withinDepth(pkgFormatted, depth) {
+			continue
+		}
+
+		_, _ = fmt.Fprintf(writer, shortHeaderTemplate, "", "", "", pkgFormatted)
+
+		files := coverageByFile[pkg]
+
+		filenames := make([]string, 0, len(files))
+		for filename := range files {
+			filenames = append(filenames, filename)
+		}
+		sort.Strings(filenames)
+
+		for _, filename := range filenames {
+			addLineStatements(writer, "    "+filename, files[filename], minCoverage)
+		}
+	}
+	addLine(writer)
+}
+
+// add a line of the short format to the output; returns false when the coverage is below the minimum
+func addLineStatements(writer io.Writer, name string, cover *statementCoverage, minCoverage float64) bool {
+	template := shortLineTemplate
+	result := true
+
+	if cover.percentage() < minCoverage {
+		template = errHighlightStart + shortLineTemplate + errHighlightEnd
+		result = false
+	}
+
+	_, _ = fmt.Fprintf(writer, template, cover.percentage(), cover.covered, cover.statements, name)
+
+	return result
+}
@@ -15,76 +15,192 @@
 package parser
 
 import (
+	"bufio"
 	"fmt"
+	"io"
+	"os"
 	"regexp"
 	"strings"
-
-	"github.com/corsc/go-tools/package-coverage/utils"
 )
 
-// coverage data line format (used to filter out the rest of the coverage file contents)
-const lineFormat = `(?i)^(([a-z0-9.\_-]+\/)+)([a-z0-9.\_-]+).go:(([0-9]+).([0-9]+)),(([0-9]+).([0-9]+))\s[0-9]+\s[0-9]+$`
-
-var lineFormatChecker = regexp.MustCompile(lineFormat)
-
 type coverage struct {
 	selfStatements int
 	selfCovered    int
 
 	childStatements int
 	childCovered    int
+
+	// timedOut is set when the tests of the package did not finish within the timeout (see addTimedOut)
+	timedOut bool
 }
 
 func (c *coverage) String() string {
 	return fmt.Sprintf("self: %d/%d / child: %d/%d", c.selfCovered, c.selfStatements, c.childCovered, c.childStatements)
 }
 
-// convert string contents of the coverage files into data structures
-func parseLines(raw string) map[string]*coverage {
-	output := make(map[string]*coverage)
+// profile contains the merged blocks of one or more coverage profiles.
+// The profiles are read line by line and each block is merged as it is read (see addBlock) so that the memory used
+// depends on the number of distinct blocks rather than the size or number of the profiles.
+type profile struct {
+	mode  string
+	mixed bool
+
+	// files contains the package and file name of each file in the profiles (shared by all the blocks of the file)
+	files map[string]*profileFile
+
+	// blocks are in the order that they were first found; index contains the position of each block in blocks
+	blocks []block
+	index  map[blockKey]int
+
+	// coveredBy contains the sources (see read) that covered each block (in the same order as blocks).  This is only
+	// recorded when attribution is set as it grows with the number of profiles.
+	coveredBy   [][]string
+	attribution bool
+
+	exclusionsMatcher *regexp.Regexp
+}
+
+// profileFile is a file in the coverage profiles
+type profileFile struct {
+	pkg  string
+	file string
+
+	// excluded is set when the file matches the exclusions
+	excluded bool
+}
+
+// blockKey identifies a block in the coverage profiles by its file and range
+type blockKey struct {
+	file *profileFile
+
+	startLine int
+	startCol  int
+	endLine   int
+	endCol    int
+}
+
+// returns an empty profile; the exclusions are applied to the files of the profiles read with exclude set
+func newProfile(exclusionsMatcher *regexp.Regexp) *profile {
+	return &profile{
+		files:             map[string]*profileFile{},
+		index:             map[blockKey]int{},
+		exclusionsMatcher: exclusionsMatcher,
+	}
+}
+
+// load a single coverage profile skipping the blocks of any files that match the exclusions
+func loadProfileFile(filename string, exclusionsMatcher *regexp.Regexp) (*profile, error) {
+	output := newProfile(exclusionsMatcher)
 
-	lines := strings.Split(raw, "\n")
-	if len(lines) == 0 {
-		utils.LogWhenVerbose("[print] no lines found in the supplied file")
-		return output
+	err := output.readFile(filename, "", exclusionsMatcher != nil)
+	if err != nil {
+		return nil, err
 	}
 
-	fragmentCh := make(chan fragment, len(lines))
-	doneCh := processFragments(output, fragmentCh)
+	return output, nil
+}
+
+// read and merge the supplied coverage profile file (see read)
+func (p *profile) readFile(filename string, source string, exclude bool) error {
+	file, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
 
-	for _, line := range lines {
-		if !validLineFormat(line) {
+	err = p.read(file, source, exclude)
+	if err != nil {
+		return fmt.Errorf("error reading coverage profile '%s': %w", filename, err)
+	}
+
+	return nil
+}
+
+// read and merge the supplied coverage profile.
+// The source is where the profile came from (e.g. the directory of the tests) and is recorded against the blocks it
+// covered when attribution is set.  When exclude is set, the blocks of the files matching the exclusions are skipped.
+func (p *profile) read(reader io.Reader, source string, exclude bool) error {
+	scanner := bufio.NewScanner(reader)
+
+	for scanner.Scan() {
+		raw := scanner.Bytes()
+
+		if len(raw) >= len(modePrefix) && string(raw[:len(modePrefix)]) == modePrefix {
+			p.addMode(strings.TrimSpace(string(raw[len(modePrefix):])))
 			continue
 		}
 
-		fragmentCh <- parseLine(line)
-	}
-	close(fragmentCh)
+		line, ok := parseLine(raw)
+		if !ok {
+			continue
+		}
+
+		file := p.getFile(line.filename)
+		if exclude && file.excluded {
+			continue
+		}
 
-	<-doneCh
+		index := p.addBlock(file, line)
+		if p.attribution && source != "" && line.count > 0 {
+			p.addCoveredBy(index, source)
+		}
+	}
 
-	return output
+	return scanner.Err()
 }
 
-func validLineFormat(line string) bool {
-	return lineFormatChecker.MatchString(line)
+// returns the file of the supplied filename (creating it when required)
+func (p *profile) getFile(filename []byte) *profileFile {
+	// the conversion does not allocate when the file already exists
+	file, found := p.files[string(filename)]
+	if found {
+		return file
+	}
+
+	name := string(filename)
+	lastSlash := strings.LastIndex(name, "/")
+
+	file = &profileFile{
+		pkg:      name[:(lastSlash + 1)],
+		file:     name[(lastSlash + 1):],
+		excluded: p.exclusionsMatcher != nil && p.exclusionsMatcher.MatchString(name),
+	}
+	p.files[name] = file
+
+	return file
 }
 
-func processFragments(output map[string]*coverage, fragmentCh chan fragment) chan struct{} {
-	doneCh := make(chan struct{})
+// returns the merged blocks (in the order that they were first found).
+// Blocks without statements (e.g. empty functions) are skipped as they cannot be covered.
+func (p *profile) getBlocks() []block {
+	output := make([]block, 0, len(p.blocks))
 
-	go func() {
-		for fragment := range fragmentCh {
-			coverage := getOrCreateCoverage(output, fragment.pkg)
-			processSelfCoverage(coverage, fragment)
+	for _, thisBlock := range p.blocks {
+		if thisBlock.statements == 0 {
+			continue
 		}
 
-		updateChildCoverage(output)
+		thisBlock.count = p.getCount(thisBlock.count)
+		output = append(output, thisBlock)
+	}
 
-		close(doneCh)
-	}()
+	return output
+}
+
+// returns the coverage of each package in the profile
+func (p *profile) getCoverage() coverageByPackage {
+	output := coverageByPackage{}
+
+	for _, thisBlock := range p.blocks {
+		cover := getOrCreateCoverage(output, thisBlock.pkg)
+		processSelfCoverage(cover, thisBlock)
+	}
+
+	updateChildCoverage(output)
 
-	return doneCh
+	return output
 }
 
 func getOrCreateCoverage(output map[string]*coverage, pkg string) *coverage {
@@ -96,29 +212,33 @@ func getOrCreateCoverage(output map[string]*coverage, pkg string) *coverage {
 	return cover
 }
 
-func processSelfCoverage(cover *coverage, fragment fragment) {
-	cover.selfStatements += fragment.statements
-	if fragment.covered {
-		cover.selfCovered += fragment.statements
+func processSelfCoverage(cover *coverage, block block) {
+	cover.selfStatements += block.statements
+	if block.count > 0 {
+		cover.selfCovered += block.statements
 	}
 }
 
-// TODO: make this more efficient
+// add the coverage of each package to the child coverage of its ancestors (in a single pass over the packages).
+// As packages end with a slash, the ancestors are the packages that are a prefix of the package.
 func updateChildCoverage(output map[string]*coverage) {
-	for pkgOuter, coverageOuter := range output {
-		for pkgInner, coverageInner := range output {
-			if pkgOuter == pkgInner {
-				continue
+	for pkg, cover := range output {
+		ancestor := pkg
+
+		for {
+			lastSlash := strings.LastIndex(strings.TrimSuffix(ancestor, "/"), "/")
+			if lastSlash == -1 {
+				break
 			}
+			ancestor = ancestor[:(lastSlash + 1)]
 
-			if isChild(pkgOuter, pkgInner) {
-				coverageOuter.childStatements += coverageInner.selfStatements
-				coverageOuter.childCovered += coverageInner.selfCovered
+			ancestorCover, found := output[ancestor]
+			if !found {
+				continue
 			}
+
+			ancestorCover.childStatements += cover.selfStatements
+			ancestorCover.childCovered += cover.selfCovered
 		}
 	}
 }
-
-func isChild(pkgA string, pkgB string) bool {
-	return strings.HasPrefix(pkgB, pkgA)
-}
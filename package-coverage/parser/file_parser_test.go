@@ -15,34 +15,41 @@
 package parser
 
 import (
+	"bufio"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 )
 
-func TestParseLines_OnePackage(t *testing.T) {
+func TestGetCoverage_OnePackage(t *testing.T) {
 	in := `mode: set
 github.com/corsc/go-tools/package-coverage/file_parser.go:13.49,15.2 1 0
 github.com/corsc/go-tools/package-coverage/line_parser.go:15.37,23.2 3 1
 github.com/corsc/go-tools/package-coverage/line_parser.go:25.40,27.21 2 1
 `
-	expected := map[string]*coverage{
+	expected := coverageByPackage{
 		"github.com/corsc/go-tools/package-coverage/": {
 			selfStatements: 6,
 			selfCovered:    5,
 		},
 	}
 
-	result := parseLines(in)
+	result := parseTestProfile(t, in).getCoverage()
 	assert.Equal(t, expected, result)
 }
 
-func TestParseLines_TwoPackages(t *testing.T) {
+func TestGetCoverage_TwoPackages(t *testing.T) {
 	in := `mode: set
 github.com/corsc/go-tools/package-coverage/file_parser.go:13.49,15.2 1 0
 github.com/corsc/go-tools/some-other-package/something.go:25.40,27.21 2 1
 `
-	expected := map[string]*coverage{
+	expected := coverageByPackage{
 		"github.com/corsc/go-tools/package-coverage/": {
 			selfStatements: 1,
 			selfCovered:    0,
@@ -53,17 +60,17 @@ github.com/corsc/go-tools/some-other-package/something.go:25.40,27.21 2 1
 		},
 	}
 
-	result := parseLines(in)
+	result := parseTestProfile(t, in).getCoverage()
 	assert.Equal(t, expected, result)
 }
 
-func TestParseLines_PackageAndChild(t *testing.T) {
+func TestGetCoverage_PackageAndChild(t *testing.T) {
 	in := `mode: set
 github.com/corsc/go-tools/package-coverage/file_parser.go:13.49,15.2 1 1
 github.com/corsc/go-tools/package-coverage/sub/file_parser.go:13.49,15.2 1 1
 github.com/corsc/go-tools/package-coverage/sub/other.go:13.49,15.2 1 0
 `
-	expected := map[string]*coverage{
+	expected := coverageByPackage{
 		"github.com/corsc/go-tools/package-coverage/": {
 			selfStatements:  1,
 			selfCovered:     1,
@@ -76,11 +83,43 @@ github.com/corsc/go-tools/package-coverage/sub/other.go:13.49,15.2 1 0
 		},
 	}
 
-	result := parseLines(in)
+	result := parseTestProfile(t, in).getCoverage()
 	assert.Equal(t, expected, result)
 }
 
-func TestValidLineFormat(t *testing.T) {
+func TestGetCoverage_Descendants(t *testing.T) {
+	// fu/bar/ has no coverage of its own; fu/ba/ is not an ancestor of fu/bar/baz/
+	in := `mode: set
+github.com/corsc/fu/a.go:13.49,15.2 1 1
+github.com/corsc/fu/ba/b.go:13.49,15.2 2 0
+github.com/corsc/fu/bar/baz/c.go:13.49,15.2 4 1
+github.com/corsc/fu/bar/baz/qux/d.go:13.49,15.2 8 0
+`
+	expected := coverageByPackage{
+		"github.com/corsc/fu/": {
+			selfStatements:  1,
+			selfCovered:     1,
+			childStatements: 14,
+			childCovered:    4,
+		},
+		"github.com/corsc/fu/ba/": {
+			selfStatements: 2,
+		},
+		"github.com/corsc/fu/bar/baz/": {
+			selfStatements:  4,
+			selfCovered:     4,
+			childStatements: 8,
+		},
+		"github.com/corsc/fu/bar/baz/qux/": {
+			selfStatements: 8,
+		},
+	}
+
+	result := parseTestProfile(t, in).getCoverage()
+	assert.Equal(t, expected, result)
+}
+
+func TestParseLine_Format(t *testing.T) {
 	scenarios := []struct {
 		desc     string
 		input    string
@@ -116,44 +155,211 @@ func TestValidLineFormat(t *testing.T) {
 			input:    "github.com/corsc/go-tools/package-coverage/line_parser.go:54.38,56.2 1 1",
 			expected: true,
 		},
-	}
-
-	for _, scenario := range scenarios {
-		result := validLineFormat(scenario.input)
-
-		assert.Equal(t, scenario.expected, result, scenario.desc)
-	}
-}
-
-func TestIsChild(t *testing.T) {
-	scenarios := []struct {
-		desc     string
-		inA      string
-		inB      string
-		expected bool
-	}{
 		{
-			desc:     "Not a child",
-			inA:      "fu/bar",
-			inB:      "google.com",
+			desc:     "valid line - windows line ending",
+			input:    "github.com/corsc/go-tools/package-coverage/line_parser.go:54.38,56.2 1 1\r",
+			expected: true,
+		},
+		{
+			desc:     "invalid line - no package",
+			input:    "line_parser.go:54.38,56.2 1 1",
 			expected: false,
 		},
 		{
-			desc:     "Is a direct child",
-			inA:      "fu.bar/com/",
-			inB:      "fu.bar/com/fu/",
-			expected: true,
+			desc:     "invalid line - no file name",
+			input:    "github.com/corsc/go-tools/package-coverage/.go:54.38,56.2 1 1",
+			expected: false,
 		},
 		{
-			desc:     "Is a sub child",
-			inA:      "fu.bar/com/",
-			inB:      "fu.bar/com/fu/bar/",
-			expected: true,
+			desc:     "invalid line - not a go file",
+			input:    "github.com/corsc/go-tools/package-coverage/line_parser.txt:54.38,56.2 1 1",
+			expected: false,
+		},
+		{
+			desc:     "invalid line - missing column",
+			input:    "github.com/corsc/go-tools/package-coverage/line_parser.go:54,56.2 1 1",
+			expected: false,
+		},
+		{
+			desc:     "invalid line - missing count",
+			input:    "github.com/corsc/go-tools/package-coverage/line_parser.go:54.38,56.2 1",
+			expected: false,
+		},
+		{
+			desc:     "invalid line - negative count",
+			input:    "github.com/corsc/go-tools/package-coverage/line_parser.go:54.38,56.2 1 -1",
+			expected: false,
 		},
 	}
 
 	for _, scenario := range scenarios {
-		result := isChild(scenario.inA, scenario.inB)
+		_, result := parseLine([]byte(scenario.input))
+
 		assert.Equal(t, scenario.expected, result, scenario.desc)
 	}
 }
+
+func TestReadProfiles_BoundedMemory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "profile")
+	assert.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+
+	small := writeTestProfile(t, dir, 1)
+	large := writeTestProfile(t, dir, 10)
+
+	allocs := func(filename string) float64 {
+		return testing.AllocsPerRun(2, func() {
+			merged, err := readProfiles(context.Background(), "", []string{filename}, LoadOptions{})
+			assert.NoError(t, err)
+			assert.Len(t, merged.getCoverage(), testProfilePackages)
+		})
+	}
+
+	// the large profile contains the blocks of the small profile 10 times; as the blocks are merged while reading,
+	// the allocations depend on the number of distinct blocks rather than the size of the profile
+	smallAllocs := allocs(small)
+	largeAllocs := allocs(large)
+	assert.True(t, largeAllocs <= smallAllocs*1.05, "allocations grew from %.0f to %.0f", smallAllocs, largeAllocs)
+}
+
+func TestReadProfiles_ManyProfiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "profile")
+	assert.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+
+	small := writeTestProfiles(t, dir, "small", 1)
+	large := writeTestProfiles(t, dir, "large", 20)
+
+	allocs := func(filenames []string) float64 {
+		return testing.AllocsPerRun(2, func() {
+			merged, err := readProfiles(context.Background(), "", filenames, LoadOptions{})
+			assert.NoError(t, err)
+			assert.Len(t, merged.getCoverage(), testProfilePackages)
+		})
+	}
+
+	// each package has its own profile containing the blocks of every package (as with -coverpkg); as the blocks are
+	// merged while reading, each additional profile only adds the allocations of finding, opening and reading it
+	// (rather than of its 20,000 blocks)
+	const allocsPerProfile = 100
+
+	smallAllocs := allocs(small)
+	largeAllocs := allocs(large)
+	assert.True(t, largeAllocs <= smallAllocs+19*allocsPerProfile, "allocations grew from %.0f to %.0f", smallAllocs, largeAllocs)
+}
+
+func BenchmarkReadProfiles(b *testing.B) {
+	dir, err := ioutil.TempDir("", "profile")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+
+	// the same blocks are repeated (as with -coverpkg) so B/op should not grow with the size of the profile
+	for _, passes := range []int{1, 10, 100} {
+		filename := writeTestProfile(b, dir, passes)
+
+		b.Run(fmt.Sprintf("passes=%d", passes), func(b *testing.B) {
+			b.ReportAllocs()
+
+			for i := 0; i < b.N; i++ {
+				merged, err := readProfiles(context.Background(), "", []string{filename}, LoadOptions{})
+				if err != nil {
+					b.Fatal(err)
+				}
+
+				_ = merged.getCoverage()
+			}
+		})
+	}
+}
+
+func BenchmarkParseLine(b *testing.B) {
+	line := []byte("github.com/corsc/go-tools/package-coverage/parser/line_parser.go:54.38,56.2 1 1")
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		_, _ = parseLine(line)
+	}
+}
+
+const (
+	testProfilePackages = 100
+	testProfileFiles    = 10
+	testProfileBlocks   = 20
+)
+
+// write a count mode coverage profile that contains the same blocks the supplied number of times
+func writeTestProfile(tb testing.TB, dir string, passes int) string {
+	filename := filepath.Join(dir, fmt.Sprintf("profile-%d.cov", passes))
+
+	file, err := os.Create(filename)
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	writer := bufio.NewWriter(file)
+	_, _ = fmt.Fprint(writer, "mode: count\n")
+
+	for pass := 0; pass < passes; pass++ {
+		for pkg := 0; pkg < testProfilePackages; pkg++ {
+			for thisFile := 0; thisFile < testProfileFiles; thisFile++ {
+				for thisBlock := 0; thisBlock < testProfileBlocks; thisBlock++ {
+					_, _ = fmt.Fprintf(writer, "github.com/corsc/fu/pkg%d/file%d.go:%d.2,%d.10 2 %d\n",
+						pkg, thisFile, thisBlock*3+1, thisBlock*3+2, pass%2)
+				}
+			}
+		}
+	}
+
+	if err := writer.Flush(); err != nil {
+		tb.Fatal(err)
+	}
+	if err := file.Close(); err != nil {
+		tb.Fatal(err)
+	}
+
+	return filename
+}
+
+// parse the supplied contents of one or more (concatenated) coverage profiles
+func parseTestProfile(t *testing.T, contents string) *profile {
+	output := newProfile(nil)
+	assert.NoError(t, output.read(strings.NewReader(contents), "", false))
+
+	return output
+}
+
+// parse the supplied contents of one or more coverage profiles into (merged) blocks
+func parseTestBlocks(t *testing.T, contents string) []block {
+	return parseTestProfile(t, contents).getBlocks()
+}
+
+// get the coverage (and the sorted packages) of the supplied contents of one or more coverage profiles
+func getTestCoverage(t *testing.T, contents string) ([]string, coverageByPackage) {
+	return getCoverageByProfile(parseTestProfile(t, contents))
+}
+
+// write the supplied number of profiles (each in its own directory, as generated for each package)
+func writeTestProfiles(tb testing.TB, dir string, name string, profiles int) []string {
+	var output []string
+
+	for index := 0; index < profiles; index++ {
+		profileDir := filepath.Join(dir, fmt.Sprintf("%s-%d", name, index))
+
+		err := os.Mkdir(profileDir, 0700)
+		if err != nil {
+			tb.Fatal(err)
+		}
+
+		output = append(output, writeTestProfile(tb, profileDir, 1))
+	}
+
+	return output
+}
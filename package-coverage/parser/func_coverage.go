@@ -0,0 +1,188 @@
+// Copyright 2017 Corey Scott http://www.sage42.org/
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io"
+	"sort"
+	"strconv"
+
+	"github.com/corsc/go-tools/package-coverage/utils"
+)
+
+const (
+	funcHeaderTemplate = "| %6s | %6s | %6s | %-54s | %-50s |\n"
+	funcLineTemplate   = "| %6.2f | %6d | %6d | %-54s | %-50s |\n"
+)
+
+// funcExtent is the location of a function declaration within a source file
+type funcExtent struct {
+	name      string
+	startLine int
+	startCol  int
+	endLine   int
+	endCol    int
+}
+
+// contains returns true when the block is inside the function
+func (f *funcExtent) contains(block block) bool {
+	if block.startLine < f.startLine || (block.startLine == f.startLine && block.startCol < f.startCol) {
+		return false
+	}
+
+	if block.endLine > f.endLine || (block.endLine == f.endLine && block.endCol > f.endCol) {
+		return false
+	}
+
+	return true
+}
+
+// funcCoverage is the coverage of a single function
+type funcCoverage struct {
+	pkg  string
+	file string
+	line int
+	name string
+
+	// count is the number of times the most executed block of the function was executed
+	count int
+
+	statementCoverage
+}
+
+// PrintFuncCoverage will print the coverage of each function in the report
+// (similar to "go tool cover -func" but with exclusions and prefix trimming applied)
+func PrintFuncCoverage(writer io.Writer, report *Report, minCoverage int, prefix string, depth int) {
+	funcs := getCoverageByFunc(report.getIncludedBlocks(), report.resolver.resolve)
+	printFuncCoverage(writer, funcs, float64(minCoverage), newPrefixer(prefix, report.Modules), depth)
+}
+
+// map the blocks onto the functions in the source files they came from
+func getCoverageByFunc(blocks []block, resolve func(pkg, file string) string) []*funcCoverage {
+	blocksByFile := map[string][]block{}
+	for _, block := range blocks {
+		blocksByFile[block.filename()] = append(blocksByFile[block.filename()], block)
+	}
+
+	var output []*funcCoverage
+
+	for _, fileBlocks := range blocksByFile {
+		pkg, file := fileBlocks[0].pkg, fileBlocks[0].file
+
+		funcs, err := findFuncs(resolve(pkg, file))
+		if err != nil {
+			utils.LogWhenVerbose("[func] unable to parse '%s%s'. err: %s", pkg, file, err)
+			continue
+		}
+
+		for _, extent := range funcs {
+			cover := &funcCoverage{
+				pkg:  pkg,
+				file: file,
+				line: extent.startLine,
+				name: extent.name,
+			}
+
+			for _, block := range fileBlocks {
+				if extent.contains(block) {
+					cover.add(block)
+
+					if block.count > cover.count {
+						cover.count = block.count
+					}
+				}
+			}
+
+			output = append(output, cover)
+		}
+	}
+
+	sort.Slice(output, func(i, j int) bool {
+		if output[i].pkg+output[i].file != output[j].pkg+output[j].file {
+			return output[i].pkg+output[i].file < output[j].pkg+output[j].file
+		}
+		return output[i].line < output[j].line
+	})
+
+	return output
+}
+
+// find the location of all the function declarations in the supplied source file
+func findFuncs(filename string) ([]*funcExtent, error) {
+	fileSet := token.NewFileSet()
+
+	file, err := parser.ParseFile(fileSet, filename, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var output []*funcExtent
+
+	for _, decl := range file.Decls {
+		funcDecl, ok := decl.(*ast.FuncDecl)
+		if !ok || funcDecl.Body == nil {
+			continue
+		}
+
+		start := fileSet.Position(funcDecl.Pos())
+		end := fileSet.Position(funcDecl.End())
+
+		output = append(output, &funcExtent{
+			name:      getFuncName(funcDecl),
+			startLine: start.Line,
+			startCol:  start.Column,
+			endLine:   end.Line,
+			endCol:    end.Column,
+		})
+	}
+
+	return output, nil
+}
+
+// returns the function name (including the receiver for methods)
+func getFuncName(funcDecl *ast.FuncDecl) string {
+	if funcDecl.Recv == nil || len(funcDecl.Recv.List) == 0 {
+		return funcDecl.Name.Name
+	}
+
+	return "(" + types.ExprString(funcDecl.Recv.List[0].Type) + ")." + funcDecl.Name.Name
+}
+
+func printFuncCoverage(writer io.Writer, funcs []*funcCoverage, minCoverage float64, prefix *prefixer, depth int) {
+	addLine(writer)
+	_, _ = fmt.Fprintf(writer, funcHeaderTemplate, "Cov%", "Cov", "Stmts", "File", "Function")
+	addLine(writer)
+
+	for _, cover := range funcs {
+		pkgFormatted := prefix.trim(cover.pkg)
+		if !withinDepth(pkgFormatted, depth) {
+			continue
+		}
+
+		template := funcLineTemplate
+		if cover.percentage() < minCoverage {
+			template = errHighlightStart + funcLineTemplate + errHighlightEnd
+		}
+
+		location := pkgFormatted + cover.file + ":" + strconv.Itoa(cover.line)
+		_, _ = fmt.Fprintf(writer, template, cover.percentage(), cover.covered, cover.statements, location, cover.name)
+	}
+	addLine(writer)
+}
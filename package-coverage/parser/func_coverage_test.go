@@ -0,0 +1,114 @@
+// Copyright 2017 Corey Scott http://www.sage42.org/
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const sampleSourceFile = `package fu
+
+func Add(a, b int) int {
+	if a > 10 {
+		return 10
+	}
+	return a + b
+}
+
+type Bar struct{}
+
+func (b *Bar) Sub(a, c int) int {
+	return a - c
+}
+`
+
+func TestGetCoverageByFile(t *testing.T) {
+	in := `mode: set
+github.com/corsc/fu/a.go:3.24,4.12 1 1
+github.com/corsc/fu/a.go:4.12,6.3 1 0
+github.com/corsc/fu/b.go:1.1,2.2 2 1
+github.com/corsc/fu/bar/c.go:1.1,2.2 4 0
+`
+	expected := map[string]map[string]*statementCoverage{
+		"github.com/corsc/fu/": {
+			"a.go": {statements: 2, covered: 1},
+			"b.go": {statements: 2, covered: 2},
+		},
+		"github.com/corsc/fu/bar/": {
+			"c.go": {statements: 4, covered: 0},
+		},
+	}
+
+	result := getCoverageByFile(parseTestBlocks(t, in))
+	assert.Equal(t, expected, result)
+
+	buffer := &bytes.Buffer{}
+	printFileCoverage(buffer, result, 0, newPrefixer("github.com/corsc/", nil), 1)
+	assert.Contains(t, buffer.String(), "|  50.00 |      1 |      2 |     a.go ")
+	assert.NotContains(t, buffer.String(), "c.go")
+}
+
+func TestGetCoverageByFunc(t *testing.T) {
+	dir, err := ioutil.TempDir("", "func-coverage")
+	assert.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+
+	err = ioutil.WriteFile(filepath.Join(dir, "a.go"), []byte(sampleSourceFile), 0600)
+	assert.NoError(t, err)
+
+	in := `mode: set
+github.com/corsc/fu/a.go:3.24,4.11 1 1
+github.com/corsc/fu/a.go:4.11,6.3 1 0
+github.com/corsc/fu/a.go:7.2,7.14 1 1
+github.com/corsc/fu/a.go:12.33,14.2 1 0
+`
+	resolve := func(pkg, file string) string {
+		return filepath.Join(dir, file)
+	}
+
+	result := getCoverageByFunc(parseTestBlocks(t, in), resolve)
+
+	expected := []*funcCoverage{
+		{
+			pkg:               "github.com/corsc/fu/",
+			file:              "a.go",
+			line:              3,
+			name:              "Add",
+			count:             1,
+			statementCoverage: statementCoverage{statements: 3, covered: 2},
+		},
+		{
+			pkg:               "github.com/corsc/fu/",
+			file:              "a.go",
+			line:              12,
+			name:              "(*Bar).Sub",
+			statementCoverage: statementCoverage{statements: 1, covered: 0},
+		},
+	}
+	assert.Equal(t, expected, result)
+
+	buffer := &bytes.Buffer{}
+	printFuncCoverage(buffer, result, 0, newPrefixer("github.com/corsc/", nil), 0)
+	assert.Contains(t, buffer.String(), "|  66.67 |      2 |      3 | fu/a.go:3 ")
+	assert.Contains(t, buffer.String(), "| (*Bar).Sub ")
+}
@@ -0,0 +1,143 @@
+// Copyright 2017 Corey Scott http://www.sage42.org/
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/corsc/go-tools/package-coverage/utils"
+)
+
+// changedLines is the set of changed (added or modified) line numbers for each file
+type changedLines map[string]map[int]struct{}
+
+// returns the lines of Go files changed between the supplied git ref and the working tree.
+// Files are returned as absolute paths.
+func getChangedLines(dir string, baseRef string) (changedLines, error) {
+	topLevel, err := runGit(dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, err
+	}
+
+	diff, err := runGit(dir, "diff", "--unified=0", "--no-color", "--no-ext-diff", baseRef, "--", "*.go")
+	if err != nil {
+		return nil, err
+	}
+
+	return parseDiff(strings.NewReader(diff), strings.TrimSpace(topLevel)), nil
+}
+
+func runGit(dir string, arguments ...string) (string, error) {
+	stderr := &bytes.Buffer{}
+
+	cmd := exec.Command("git", arguments...)
+	cmd.Dir = dir
+	cmd.Stderr = stderr
+
+	payload, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("error running 'git %s' in '%s'. err: %s %s", strings.Join(arguments, " "), dir, err, strings.TrimSpace(stderr.String()))
+	}
+
+	return string(payload), nil
+}
+
+// parse the output of "git diff --unified=0" into the changed lines of each file
+func parseDiff(in io.Reader, topLevel string) changedLines {
+	output := changedLines{}
+
+	var currentFile map[int]struct{}
+
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		switch {
+		case strings.HasPrefix(line, "+++ "):
+			currentFile = nil
+
+			filename := getDiffFilename(strings.TrimPrefix(line, "+++ "))
+			if filename == "/dev/null" {
+				// file was deleted
+				continue
+			}
+
+			filename = filepath.Join(topLevel, strings.TrimPrefix(filename, "b/"))
+			currentFile = map[int]struct{}{}
+			output[filename] = currentFile
+
+		case strings.HasPrefix(line, "@@ ") && currentFile != nil:
+			start, count := parseHunkHeader(line)
+			for lineNo := start; lineNo < start+count; lineNo++ {
+				currentFile[lineNo] = struct{}{}
+			}
+		}
+	}
+
+	return output
+}
+
+// returns the filename of a "+++" line of the diff.
+// git quotes filenames with special (or non-ASCII) characters and adds a tab after filenames that contain spaces.
+func getDiffFilename(raw string) string {
+	raw = strings.TrimSuffix(raw, "\t")
+
+	if strings.HasPrefix(raw, `"`) {
+		filename, err := strconv.Unquote(raw)
+		if err != nil {
+			utils.LogWhenVerbose("[diff] unable to unquote filename '%s'. err: %s", raw, err)
+			return raw
+		}
+
+		return filename
+	}
+
+	return raw
+}
+
+// extract the new line range from a hunk header of the format "@@ -a,b +c,d @@"
+func parseHunkHeader(line string) (int, int) {
+	parts := strings.Split(line, " ")
+	if len(parts) < 3 || !strings.HasPrefix(parts[2], "+") {
+		utils.LogWhenVerbose("[diff] skipped invalid hunk header '%s'", line)
+		return 0, 0
+	}
+
+	newRange := strings.Split(strings.TrimPrefix(parts[2], "+"), ",")
+
+	start, err := strconv.Atoi(newRange[0])
+	if err != nil {
+		utils.LogWhenVerbose("[diff] skipped invalid hunk header '%s'", line)
+		return 0, 0
+	}
+
+	count := 1
+	if len(newRange) > 1 {
+		count, err = strconv.Atoi(newRange[1])
+		if err != nil {
+			utils.LogWhenVerbose("[diff] skipped invalid hunk header '%s'", line)
+			return 0, 0
+		}
+	}
+
+	return start, count
+}
@@ -0,0 +1,210 @@
+// Copyright 2017 Corey Scott http://www.sage42.org/
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/corsc/go-tools/package-coverage/utils"
+)
+
+const (
+	hotLineTemplate  = "        %12d  %-30s  %s\n"
+	maxExcerptLength = 80
+)
+
+// PrintHotPaths will print the most and least executed blocks and functions of each package in the report (with
+// source excerpts).  This is only possible when the coverage was calculated with the count or atomic covermode.
+func PrintHotPaths(writer io.Writer, report *Report, limit int, prefix string, depth int) {
+	blocks := report.getIncludedBlocks()
+	resolve := report.resolver.resolve
+
+	printHotPaths(writer, report.merged.getMode(), blocks, getCoverageByFunc(blocks, resolve), resolve, limit, newPrefixer(prefix, report.Modules), depth)
+}
+
+func printHotPaths(writer io.Writer, mode string, blocks []block, funcs []*funcCoverage, resolve func(pkg, file string) string, limit int, prefix *prefixer, depth int) {
+	if mode != "count" && mode != "atomic" {
+		utils.LogAlways("[hot] the most and least executed code requires -covermode=count or atomic (found '%s')", mode)
+		return
+	}
+
+	blocksByPkg := map[string][]block{}
+	for _, thisBlock := range blocks {
+		blocksByPkg[thisBlock.pkg] = append(blocksByPkg[thisBlock.pkg], thisBlock)
+	}
+
+	funcsByPkg := map[string][]*funcCoverage{}
+	for _, cover := range funcs {
+		funcsByPkg[cover.pkg] = append(funcsByPkg[cover.pkg], cover)
+	}
+
+	pkgs := make([]string, 0, len(blocksByPkg))
+	for pkg := range blocksByPkg {
+		pkgs = append(pkgs, pkg)
+	}
+	sort.Strings(pkgs)
+
+	excerpts := newExcerptLoader(resolve)
+
+	_, _ = fmt.Fprintf(writer, "Most and least executed code of each package (covermode: %s)\n", mode)
+	addLine(writer)
+
+	for _, pkg := range pkgs {
+		pkgFormatted := prefix.trim(pkg)
+		if !withinDepth(pkgFormatted, depth) {
+			continue
+		}
+
+		_, _ = fmt.Fprintf(writer, "%s\n", pkgFormatted)
+
+		pkgBlocks := blocksByPkg[pkg]
+		sortBlocksByCount(pkgBlocks, true)
+		printHotBlocks(writer, "Most executed blocks", pkgBlocks, limit, excerpts)
+
+		sortBlocksByCount(pkgBlocks, false)
+		printHotBlocks(writer, "Least executed blocks", pkgBlocks, limit, excerpts)
+
+		pkgFuncs := funcsByPkg[pkg]
+		sortFuncsByCount(pkgFuncs, true)
+		printHotFuncs(writer, "Most executed functions", pkgFuncs, limit)
+
+		sortFuncsByCount(pkgFuncs, false)
+		printHotFuncs(writer, "Least executed functions", pkgFuncs, limit)
+	}
+	addLine(writer)
+}
+
+func printHotBlocks(writer io.Writer, title string, blocks []block, limit int, excerpts *excerptLoader) {
+	_, _ = fmt.Fprintf(writer, "    %s\n", title)
+
+	for index := 0; index < limit && index < len(blocks); index++ {
+		thisBlock := blocks[index]
+		location := thisBlock.file + ":" + strconv.Itoa(thisBlock.startLine)
+		_, _ = fmt.Fprintf(writer, hotLineTemplate, thisBlock.count, location, excerpts.get(thisBlock))
+	}
+}
+
+func printHotFuncs(writer io.Writer, title string, funcs []*funcCoverage, limit int) {
+	if len(funcs) == 0 {
+		return
+	}
+
+	_, _ = fmt.Fprintf(writer, "    %s\n", title)
+
+	for index := 0; index < limit && index < len(funcs); index++ {
+		cover := funcs[index]
+		location := cover.file + ":" + strconv.Itoa(cover.line)
+		_, _ = fmt.Fprintf(writer, hotLineTemplate, cover.count, location, cover.name)
+	}
+}
+
+// sort the blocks by count (ties are sorted by location)
+func sortBlocksByCount(blocks []block, descending bool) {
+	sort.Slice(blocks, func(i, j int) bool {
+		if blocks[i].count != blocks[j].count {
+			return (blocks[i].count > blocks[j].count) == descending
+		}
+
+		if blocks[i].file != blocks[j].file {
+			return blocks[i].file < blocks[j].file
+		}
+
+		return blocks[i].startLine < blocks[j].startLine || (blocks[i].startLine == blocks[j].startLine && blocks[i].startCol < blocks[j].startCol)
+	})
+}
+
+// sort the functions by count (ties are sorted by location)
+func sortFuncsByCount(funcs []*funcCoverage, descending bool) {
+	sort.Slice(funcs, func(i, j int) bool {
+		if funcs[i].count != funcs[j].count {
+			return (funcs[i].count > funcs[j].count) == descending
+		}
+
+		if funcs[i].file != funcs[j].file {
+			return funcs[i].file < funcs[j].file
+		}
+
+		return funcs[i].line < funcs[j].line
+	})
+}
+
+// excerptLoader returns the source code of blocks (caching the source files)
+type excerptLoader struct {
+	resolve func(pkg, file string) string
+	files   map[string][]string
+}
+
+func newExcerptLoader(resolve func(pkg, file string) string) *excerptLoader {
+	return &excerptLoader{
+		resolve: resolve,
+		files:   map[string][]string{},
+	}
+}
+
+// returns the source of the block on a single line (truncated when long)
+func (e *excerptLoader) get(thisBlock block) string {
+	return getExcerpt(e.lines(thisBlock), thisBlock)
+}
+
+// returns the lines of the source file containing the block (nil when it cannot be read)
+func (e *excerptLoader) lines(thisBlock block) []string {
+	lines, found := e.files[thisBlock.filename()]
+	if !found {
+		contents, err := ioutil.ReadFile(e.resolve(thisBlock.pkg, thisBlock.file))
+		if err != nil {
+			utils.LogWhenVerbose("[hot] unable to read source of '%s'. err: %s", thisBlock.filename(), err)
+		} else {
+			lines = strings.Split(string(contents), "\n")
+		}
+
+		e.files[thisBlock.filename()] = lines
+	}
+
+	return lines
+}
+
+// returns the source between the start and end of the block (columns are 1-based byte offsets)
+func getExcerpt(lines []string, thisBlock block) string {
+	var parts []string
+
+	for number := thisBlock.startLine; number <= thisBlock.endLine && number <= len(lines); number++ {
+		line := lines[number-1]
+
+		if number == thisBlock.endLine && thisBlock.endCol-1 <= len(line) {
+			line = line[:(thisBlock.endCol - 1)]
+		}
+
+		if number == thisBlock.startLine && thisBlock.startCol-1 <= len(line) {
+			line = line[(thisBlock.startCol - 1):]
+		}
+
+		if line = strings.TrimSpace(line); line != "" {
+			parts = append(parts, line)
+		}
+	}
+
+	// blocks can start with the opening brace of their body (which is noise)
+	excerpt := []rune(strings.TrimSpace(strings.TrimPrefix(strings.Join(parts, " "), "{")))
+	if len(excerpt) > maxExcerptLength {
+		return string(excerpt[:maxExcerptLength]) + "..."
+	}
+
+	return string(excerpt)
+}
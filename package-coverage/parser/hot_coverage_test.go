@@ -0,0 +1,79 @@
+// Copyright 2017 Corey Scott http://www.sage42.org/
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetExcerpt(t *testing.T) {
+	lines := strings.Split(sampleSourceFile, "\n")
+
+	assert.Equal(t, "if a > 10", getExcerpt(lines, block{startLine: 3, startCol: 24, endLine: 4, endCol: 11}))
+	assert.Equal(t, "return 10 }", getExcerpt(lines, block{startLine: 4, startCol: 11, endLine: 6, endCol: 3}))
+	assert.Equal(t, "", getExcerpt(lines, block{startLine: 100, startCol: 1, endLine: 101, endCol: 1}))
+
+	long := []string{strings.Repeat("x", 100)}
+	assert.Equal(t, strings.Repeat("x", maxExcerptLength)+"...", getExcerpt(long, block{startLine: 1, startCol: 1, endLine: 1, endCol: 101}))
+}
+
+func TestPrintHotPaths(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hot-coverage")
+	assert.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+
+	err = ioutil.WriteFile(filepath.Join(dir, "a.go"), []byte(sampleSourceFile), 0600)
+	assert.NoError(t, err)
+
+	in := `mode: count
+github.com/corsc/fu/a.go:3.24,4.11 1 120
+github.com/corsc/fu/a.go:4.11,6.3 1 0
+github.com/corsc/fu/a.go:7.2,7.14 1 120
+github.com/corsc/fu/a.go:12.33,14.2 1 3
+`
+	resolve := func(pkg, file string) string {
+		return filepath.Join(dir, file)
+	}
+
+	blocks := parseTestBlocks(t, in)
+	buffer := &bytes.Buffer{}
+	printHotPaths(buffer, parseTestProfile(t, in).getMode(), blocks, getCoverageByFunc(blocks, resolve), resolve, 1, newPrefixer("github.com/corsc/", nil), 0)
+
+	expected := `fu/
+    Most executed blocks
+                 120  a.go:3                          if a > 10
+    Least executed blocks
+                   0  a.go:4                          return 10 }
+    Most executed functions
+                 120  a.go:3                          Add
+    Least executed functions
+                   3  a.go:12                         (*Bar).Sub
+`
+	assert.Contains(t, buffer.String(), expected)
+
+	// set mode has no counts
+	buffer.Reset()
+	printHotPaths(buffer, "set", blocks, nil, resolve, 1, newPrefixer("github.com/corsc/", nil), 0)
+	assert.Empty(t, buffer.String())
+}
@@ -0,0 +1,303 @@
+// Copyright 2017 Corey Scott http://www.sage42.org/
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/corsc/go-tools/package-coverage/utils"
+)
+
+const htmlStyle = `
+body { font-family: sans-serif; font-size: 14px; margin: 20px; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ccc; padding: 2px 8px; }
+td.num { text-align: right; font-family: monospace; }
+tr.low td { color: #c00; }
+table.source td { border: none; padding: 0 8px; font-family: monospace; white-space: pre; }
+tr.covered { background: #dfd; }
+tr.uncovered { background: #fdd; }
+`
+
+var htmlTemplates = template.Must(template.New("index").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Coverage</title><style>` + htmlStyle + `</style></head>
+<body>
+<h1>Coverage</h1>
+<table>
+<tr><th colspan="3">Branch</th><th colspan="3">Dir</th><th rowspan="2">Package</th></tr>
+<tr><th>Cov%</th><th>Cov</th><th>Stmts</th><th>Cov%</th><th>Cov</th><th>Stmts</th></tr>
+{{- range .}}
+<tr{{if .Low}} class="low"{{end}}>
+<td class="num">{{printf "%.2f" .BranchPercent}}</td><td class="num">{{.BranchCovered}}</td><td class="num">{{.BranchStatements}}</td>
+<td class="num">{{printf "%.2f" .DirPercent}}</td><td class="num">{{.DirCovered}}</td><td class="num">{{.DirStatements}}</td>
+<td style="padding-left: {{.Indent}}em"><a href="{{.Page}}">{{.Name}}</a></td>
+</tr>
+{{- end}}
+</table>
+</body>
+</html>
+`))
+
+func init() {
+	template.Must(htmlTemplates.New("package").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>{{.Name}}</title><style>` + htmlStyle + `</style></head>
+<body>
+<p><a href="index.html">Coverage</a></p>
+<h1>{{.Name}}</h1>
+<table>
+<tr><th>Cov%</th><th>Cov</th><th>Stmts</th><th>File</th></tr>
+{{- range .Files}}
+<tr{{if .Low}} class="low"{{end}}>
+<td class="num">{{printf "%.2f" .Percent}}</td><td class="num">{{.Covered}}</td><td class="num">{{.Statements}}</td>
+<td><a href="{{.Page}}">{{.Name}}</a></td>
+</tr>
+{{- end}}
+</table>
+</body>
+</html>
+`))
+
+	template.Must(htmlTemplates.New("file").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>{{.Name}}</title><style>` + htmlStyle + `</style></head>
+<body>
+<p><a href="index.html">Coverage</a> / <a href="{{.PackagePage}}">{{.Package}}</a></p>
+<h1>{{.Name}}</h1>
+{{- if .Lines}}
+<table class="source">
+{{- range .Lines}}
+<tr{{if .Class}} class="{{.Class}}"{{end}}><td class="num">{{.Number}}</td><td class="num">{{.Hits}}</td><td>{{.Source}}</td></tr>
+{{- end}}
+</table>
+{{- else}}
+<p>Source not available</p>
+{{- end}}
+</body>
+</html>
+`))
+}
+
+type htmlPackage struct {
+	pkg string
+
+	Name   string
+	Page   string
+	Indent int
+	Low    bool
+
+	BranchPercent    float64
+	BranchCovered    int
+	BranchStatements int
+	DirPercent       float64
+	DirCovered       int
+	DirStatements    int
+
+	Files []*htmlFile
+}
+
+type htmlFile struct {
+	Name       string
+	Page       string
+	Low        bool
+	Percent    float64
+	Covered    int
+	Statements int
+
+	Package     string
+	PackagePage string
+	Lines       []*htmlLine
+}
+
+type htmlLine struct {
+	Number int
+	Hits   string
+	Class  string
+	Source string
+}
+
+// HTMLCoverage will write a static HTML report of the coverage in the report into the output directory.
+// The index contains the package tree (with prefix and depth applied) and links to annotated sources of each file.
+func HTMLCoverage(outputDir string, report *Report, minCoverage int, prefix string, depth int) error {
+	blocks := report.getIncludedBlocks()
+
+	return writeHTML(outputDir, buildHTMLPackages(report.pkgs, report.coverageData, blocks, float64(minCoverage), newPrefixer(prefix, report.Modules), depth), report.resolver.resolve, blocks)
+}
+
+func buildHTMLPackages(pkgs []string, coverageData coverageByPackage, blocks []block, minCoverage float64, prefix *prefixer, depth int) []*htmlPackage {
+	coverageByFile := getCoverageByFile(blocks)
+
+	var output []*htmlPackage
+	minDepth := -1
+
+	for _, pkg := range pkgs {
+		pkgFormatted := prefix.trim(pkg)
+		if !withinDepth(pkgFormatted, depth) {
+			continue
+		}
+
+		cover := coverageData[pkg]
+		branchPercent, _, _ := getSummaryValues(cover)
+		dirPercent, _, _ := getSelfValues(cover)
+
+		name := pkgFormatted
+		if cover.timedOut {
+			name += timedOutSuffix
+		}
+
+		htmlPkg := &htmlPackage{
+			pkg:              pkg,
+			Name:             name,
+			Page:             getHTMLPage("pkg", pkg),
+			Indent:           strings.Count(pkgFormatted, "/"),
+			Low:              branchPercent < minCoverage || cover.timedOut,
+			BranchPercent:    branchPercent,
+			BranchCovered:    cover.selfCovered + cover.childCovered,
+			BranchStatements: cover.selfStatements + cover.childStatements,
+			DirPercent:       dirPercent,
+			DirCovered:       cover.selfCovered,
+			DirStatements:    cover.selfStatements,
+		}
+
+		if minDepth == -1 || htmlPkg.Indent < minDepth {
+			minDepth = htmlPkg.Indent
+		}
+
+		files := coverageByFile[pkg]
+		filenames := make([]string, 0, len(files))
+		for filename := range files {
+			filenames = append(filenames, filename)
+		}
+		sort.Strings(filenames)
+
+		for _, filename := range filenames {
+			fileCover := files[filename]
+
+			htmlPkg.Files = append(htmlPkg.Files, &htmlFile{
+				Name:        filename,
+				Page:        getHTMLPage("file", pkg+filename),
+				Low:         fileCover.percentage() < minCoverage,
+				Percent:     fileCover.percentage(),
+				Covered:     fileCover.covered,
+				Statements:  fileCover.statements,
+				Package:     pkgFormatted,
+				PackagePage: htmlPkg.Page,
+			})
+		}
+
+		output = append(output, htmlPkg)
+	}
+
+	// indent relative to the shallowest package
+	for _, htmlPkg := range output {
+		htmlPkg.Indent = (htmlPkg.Indent - minDepth) * 2
+	}
+
+	return output
+}
+
+func writeHTML(outputDir string, pkgs []*htmlPackage, resolve func(pkg, file string) string, blocks []block) error {
+	err := os.MkdirAll(outputDir, 0755)
+	if err != nil {
+		return fmt.Errorf("error creating HTML output directory '%s': %w", outputDir, err)
+	}
+
+	err = writeHTMLPage(filepath.Join(outputDir, "index.html"), "index", pkgs)
+	if err != nil {
+		return err
+	}
+
+	lineHitsByFile := getLineHitsByFile(blocks)
+
+	for _, pkg := range pkgs {
+		err = writeHTMLPage(filepath.Join(outputDir, pkg.Page), "package", pkg)
+		if err != nil {
+			return err
+		}
+
+		for _, file := range pkg.Files {
+			file.Lines = getHTMLLines(resolve(pkg.pkg, file.Name), lineHitsByFile[pkg.pkg+file.Name])
+
+			err = writeHTMLPage(filepath.Join(outputDir, file.Page), "file", file)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// annotate each line of the source file with the number of times it was executed
+func getHTMLLines(filename string, lineHits map[int]int) []*htmlLine {
+	contents, err := ioutil.ReadFile(filename)
+	if err != nil {
+		utils.LogAlways("[html] unable to read source file '%s'. err: %s", filename, err)
+		return nil
+	}
+
+	var output []*htmlLine
+	for index, source := range strings.Split(string(contents), "\n") {
+		line := &htmlLine{
+			Number: index + 1,
+			Source: source,
+		}
+
+		if hits, found := lineHits[line.Number]; found {
+			line.Hits = strconv.Itoa(hits)
+			line.Class = "uncovered"
+			if hits > 0 {
+				line.Class = "covered"
+			}
+		}
+
+		output = append(output, line)
+	}
+
+	return output
+}
+
+func writeHTMLPage(filename string, templateName string, data interface{}) (err error) {
+	output, err := createOutput(filename)
+	if err != nil {
+		return err
+	}
+	defer closeOutput(filename, output, &err)
+
+	return executeHTMLTemplate(output, templateName, data)
+}
+
+func executeHTMLTemplate(writer io.Writer, templateName string, data interface{}) error {
+	err := htmlTemplates.ExecuteTemplate(writer, templateName, data)
+	if err != nil {
+		return fmt.Errorf("error generating HTML page '%s': %w", templateName, err)
+	}
+
+	return nil
+}
+
+// returns a filesystem safe HTML page name for the supplied package or file (see escapeFilename)
+func getHTMLPage(kind string, name string) string {
+	return kind + "-" + escapeFilename(name) + ".html"
+}
@@ -0,0 +1,75 @@
+// Copyright 2017 Corey Scott http://www.sage42.org/
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildHTMLPackages(t *testing.T) {
+	in := `mode: set
+github.com/corsc/fu/a.go:3.24,4.12 1 1
+github.com/corsc/fu/a.go:4.12,6.3 1 0
+github.com/corsc/fu/bar/b.go:1.1,2.2 2 1
+github.com/corsc/fu/bar/baz/c.go:1.1,2.2 4 0
+`
+	pkgs, coverageData := getTestCoverage(t, in)
+
+	result := buildHTMLPackages(pkgs, coverageData, parseTestBlocks(t, in), 50, newPrefixer("github.com/corsc/", nil), 2)
+	assert.Len(t, result, 2)
+
+	assert.Equal(t, "fu/", result[0].Name)
+	assert.Equal(t, "pkg-github.com_corsc_fu.html", result[0].Page)
+	assert.Equal(t, 0, result[0].Indent)
+	assert.Equal(t, 3, result[0].BranchCovered)
+	assert.Equal(t, 8, result[0].BranchStatements)
+	assert.True(t, result[0].Low)
+	assert.Len(t, result[0].Files, 1)
+	assert.Equal(t, "file-github.com_corsc_fu_a.go.html", result[0].Files[0].Page)
+
+	assert.Equal(t, "fu/bar/", result[1].Name)
+	assert.Equal(t, 2, result[1].Indent)
+	assert.Equal(t, 6, result[1].BranchStatements)
+	assert.Equal(t, 2, result[1].DirStatements)
+
+	buffer := &bytes.Buffer{}
+	assert.NoError(t, executeHTMLTemplate(buffer, "index", result))
+	assert.Contains(t, buffer.String(), `<td style="padding-left: 2em"><a href="pkg-github.com_corsc_fu_bar.html">fu/bar/</a></td>`)
+}
+
+func TestGetHTMLLines(t *testing.T) {
+	result := getHTMLLines("../test-data/pathmatcher/path_matcher.go", map[int]int{2: 0, 3: 3})
+
+	assert.Equal(t, "", result[0].Class)
+	assert.Equal(t, "uncovered", result[1].Class)
+	assert.Equal(t, "covered", result[2].Class)
+	assert.Equal(t, "3", result[2].Hits)
+}
+
+func TestGetHTMLPage(t *testing.T) {
+	assert.Equal(t, "pkg-github.com_corsc_fu.html", getHTMLPage("pkg", "github.com/corsc/fu/"))
+	assert.Equal(t, "file-a_foo-5fbar_b.go.html", getHTMLPage("file", "a/foo_bar/b.go"))
+
+	// packages and files whose names only differ by slashes, underscores or dashes have different pages
+	pages := map[string]string{}
+	for _, name := range []string{"a/foo_bar/", "a/foo/bar/", "a/foo-bar/", "a/_/", "a_/", "a/foo_bar/b.go", "a/foo/bar/b.go"} {
+		page := getHTMLPage("pkg", name)
+		assert.NotContains(t, pages, page, name)
+		pages[page] = name
+	}
+}
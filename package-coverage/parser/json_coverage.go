@@ -0,0 +1,94 @@
+// Copyright 2017 Corey Scott http://www.sage42.org/
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// jsonReport is the top level of the JSON output
+type jsonReport struct {
+	MinCoverage int            `json:"minCoverage"`
+	Packages    []*jsonPackage `json:"packages"`
+}
+
+// jsonPackage is the JSON output for a single package
+type jsonPackage struct {
+	Package string `json:"package"`
+	Depth   int    `json:"depth"`
+
+	SelfStatements  int `json:"selfStatements"`
+	SelfCovered     int `json:"selfCovered"`
+	ChildStatements int `json:"childStatements"`
+	ChildCovered    int `json:"childCovered"`
+
+	BranchPercent float64 `json:"branchPercent"`
+	DirPercent    float64 `json:"dirPercent"`
+
+	BelowMinimum bool `json:"belowMinimum"`
+
+	// TimedOut is set when the tests of the package did not finish within the timeout (the coverage is incomplete)
+	TimedOut bool `json:"timedOut,omitempty"`
+}
+
+// JSONCoverage will output the coverage in the report as JSON.
+// Unlike the console output, all packages are included regardless of depth.
+func JSONCoverage(writer io.Writer, report *Report, minCoverage int, prefix string) error {
+	return writeJSON(writer, report.pkgs, report.coverageData, minCoverage, newPrefixer(prefix, report.Modules))
+}
+
+func writeJSON(writer io.Writer, pkgs []string, coverageData coverageByPackage, minCoverage int, prefix *prefixer) error {
+	report := &jsonReport{
+		MinCoverage: minCoverage,
+		Packages:    make([]*jsonPackage, 0, len(pkgs)),
+	}
+
+	for _, pkg := range pkgs {
+		report.Packages = append(report.Packages, buildJSONPackage(pkg, coverageData[pkg], float64(minCoverage), prefix))
+	}
+
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+
+	err := encoder.Encode(report)
+	if err != nil {
+		return fmt.Errorf("error encoding coverage as JSON: %w", err)
+	}
+
+	return nil
+}
+
+func buildJSONPackage(pkg string, cover *coverage, minCoverage float64, prefix *prefixer) *jsonPackage {
+	pkgFormatted := prefix.trim(pkg)
+
+	branchPercent, _, _ := getSummaryValues(cover)
+	dirPercent, _, _ := getSelfValues(cover)
+
+	return &jsonPackage{
+		Package:         pkgFormatted,
+		Depth:           strings.Count(pkgFormatted, "/"),
+		SelfStatements:  cover.selfStatements,
+		SelfCovered:     cover.selfCovered,
+		ChildStatements: cover.childStatements,
+		ChildCovered:    cover.childCovered,
+		BranchPercent:   branchPercent,
+		DirPercent:      dirPercent,
+		BelowMinimum:    branchPercent < minCoverage || cover.timedOut,
+		TimedOut:        cover.timedOut,
+	}
+}
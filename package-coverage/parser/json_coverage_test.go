@@ -0,0 +1,121 @@
+// Copyright 2017 Corey Scott http://www.sage42.org/
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteJSON(t *testing.T) {
+	coverageData := coverageByPackage{
+		"github.com/corsc/go-tools/package-coverage/": {
+			selfStatements:  4,
+			selfCovered:     3,
+			childStatements: 6,
+			childCovered:    3,
+		},
+		"github.com/corsc/go-tools/package-coverage/parser/": {
+			selfStatements: 6,
+			selfCovered:    3,
+		},
+	}
+	pkgs := getSortedPackages(coverageData)
+
+	buffer := &bytes.Buffer{}
+	assert.NoError(t, writeJSON(buffer, pkgs, coverageData, 60, newPrefixer("github.com/corsc/go-tools/", nil)))
+
+	expected := `{
+  "minCoverage": 60,
+  "packages": [
+    {
+      "package": "package-coverage/",
+      "depth": 1,
+      "selfStatements": 4,
+      "selfCovered": 3,
+      "childStatements": 6,
+      "childCovered": 3,
+      "branchPercent": 60,
+      "dirPercent": 75,
+      "belowMinimum": false
+    },
+    {
+      "package": "package-coverage/parser/",
+      "depth": 2,
+      "selfStatements": 6,
+      "selfCovered": 3,
+      "childStatements": 0,
+      "childCovered": 0,
+      "branchPercent": 50,
+      "dirPercent": 50,
+      "belowMinimum": true
+    }
+  ]
+}
+`
+	assert.Equal(t, expected, buffer.String())
+}
+
+func TestWriteJSON_TimedOut(t *testing.T) {
+	coverageData := coverageByPackage{
+		"github.com/corsc/go-tools/package-coverage/": {
+			selfStatements: 4,
+			selfCovered:    4,
+		},
+	}
+	results := []*testResults{
+		{pkg: "github.com/corsc/go-tools/package-coverage/"},
+		{pkg: "github.com/corsc/go-tools/package-coverage/parser/", timedOut: true},
+	}
+	pkgs, coverageData := addTimedOut(getSortedPackages(coverageData), coverageData, results)
+
+	assert.Equal(t, []string{"github.com/corsc/go-tools/package-coverage/", "github.com/corsc/go-tools/package-coverage/parser/"}, pkgs)
+
+	buffer := &bytes.Buffer{}
+	assert.NoError(t, writeJSON(buffer, pkgs, coverageData, 60, newPrefixer("github.com/corsc/go-tools/", nil)))
+
+	expected := `{
+  "minCoverage": 60,
+  "packages": [
+    {
+      "package": "package-coverage/",
+      "depth": 1,
+      "selfStatements": 4,
+      "selfCovered": 4,
+      "childStatements": 0,
+      "childCovered": 0,
+      "branchPercent": 100,
+      "dirPercent": 100,
+      "belowMinimum": false
+    },
+    {
+      "package": "package-coverage/parser/",
+      "depth": 2,
+      "selfStatements": 0,
+      "selfCovered": 0,
+      "childStatements": 0,
+      "childCovered": 0,
+      "branchPercent": 100,
+      "dirPercent": 100,
+      "belowMinimum": true,
+      "timedOut": true
+    }
+  ]
+}
+`
+	assert.Equal(t, expected, buffer.String())
+}
@@ -0,0 +1,157 @@
+// Copyright 2017 Corey Scott http://www.sage42.org/
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+type junitTestSuites struct {
+	XMLName  xml.Name          `xml:"testsuites"`
+	Tests    int               `xml:"tests,attr"`
+	Failures int               `xml:"failures,attr"`
+	Errors   int               `xml:"errors,attr"`
+	Skipped  int               `xml:"skipped,attr"`
+	Time     string            `xml:"time,attr"`
+	Suites   []*junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string           `xml:"name,attr"`
+	Tests     int              `xml:"tests,attr"`
+	Failures  int              `xml:"failures,attr"`
+	Errors    int              `xml:"errors,attr"`
+	Skipped   int              `xml:"skipped,attr"`
+	Time      string           `xml:"time,attr"`
+	TestCases []*junitTestCase `xml:"testcase"`
+	SystemOut string           `xml:"system-out,omitempty"`
+}
+
+type junitTestCase struct {
+	ClassName string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Error     *junitMessage `xml:"error,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitMessage struct {
+	Message  string `xml:"message,attr"`
+	Type     string `xml:"type,attr,omitempty"`
+	Contents string `xml:",chardata"`
+}
+
+// JUnitReport will output the results of the tests in the report as JUnit XML.
+// There is 1 test suite per package (named with the full package name).
+func JUnitReport(writer io.Writer, report *Report) error {
+	return writeJUnit(writer, buildJUnit(report.results))
+}
+
+func writeJUnit(writer io.Writer, report *junitTestSuites) error {
+	_, _ = io.WriteString(writer, xml.Header)
+
+	encoder := xml.NewEncoder(writer)
+	encoder.Indent("", "  ")
+
+	err := encoder.Encode(report)
+	if err != nil {
+		return fmt.Errorf("error encoding test results as JUnit XML: %w", err)
+	}
+
+	_, _ = io.WriteString(writer, "\n")
+	return nil
+}
+
+func buildJUnit(results []*testResults) *junitTestSuites {
+	report := &junitTestSuites{}
+	elapsed := 0.0
+
+	for _, pkgResults := range results {
+		pkg := strings.TrimSuffix(pkgResults.pkg, "/")
+
+		suite := &junitTestSuite{
+			Name:     pkg,
+			Tests:    len(pkgResults.tests),
+			Failures: pkgResults.failed,
+			Skipped:  pkgResults.skipped,
+			Time:     getJUnitTime(pkgResults.elapsed),
+		}
+
+		for _, test := range pkgResults.tests {
+			testCase := &junitTestCase{
+				ClassName: pkg,
+				Name:      test.name,
+				Time:      getJUnitTime(test.elapsed),
+			}
+
+			switch test.action {
+			case "fail":
+				testCase.Failure = &junitMessage{Message: "Failed", Contents: test.output}
+
+			case "skip":
+				testCase.Skipped = &junitMessage{Message: "Skipped", Contents: test.output}
+
+			default:
+				testCase.SystemOut = test.output
+			}
+
+			suite.TestCases = append(suite.TestCases, testCase)
+		}
+
+		// a package that failed as a whole (e.g. build failure or timeout) is reported as an error so that it is not missed
+		if pkgResults.timedOut {
+			suite.Tests++
+			suite.Errors++
+			suite.TestCases = append(suite.TestCases, &junitTestCase{
+				ClassName: pkg,
+				Name:      "[package timed out]",
+				Time:      getJUnitTime(pkgResults.duration()),
+				Error:     &junitMessage{Message: "Timed out", Type: "timeout", Contents: pkgResults.output},
+			})
+		} else if pkgResults.packageFailed {
+			suite.Tests++
+			suite.Errors++
+			suite.TestCases = append(suite.TestCases, &junitTestCase{
+				ClassName: pkg,
+				Name:      "[package failed]",
+				Time:      getJUnitTime(pkgResults.elapsed),
+				Error:     &junitMessage{Message: "Failed", Contents: pkgResults.output},
+			})
+		} else {
+			suite.SystemOut = pkgResults.output
+		}
+
+		report.Tests += suite.Tests
+		report.Failures += suite.Failures
+		report.Errors += suite.Errors
+		report.Skipped += suite.Skipped
+		report.Suites = append(report.Suites, suite)
+
+		elapsed += pkgResults.elapsed
+	}
+
+	report.Time = getJUnitTime(elapsed)
+	return report
+}
+
+// format the supplied duration in seconds (as used by JUnit)
+func getJUnitTime(elapsed float64) string {
+	return fmt.Sprintf("%.3f", elapsed)
+}
@@ -0,0 +1,76 @@
+// Copyright 2017 Corey Scott http://www.sage42.org/
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildJUnit(t *testing.T) {
+	results := []*testResults{
+		{
+			pkg:      "github.com/corsc/fu/",
+			passed:   1,
+			skipped:  1,
+			failed:   1,
+			failures: []string{"TestA"},
+			elapsed:  1.5,
+			output:   "FAIL\n",
+			tests: []*testCase{
+				{name: "TestA", action: "fail", elapsed: 0.25, output: "boom\n"},
+				{name: "TestB", action: "pass", output: "ok\n"},
+				{name: "TestC", action: "skip"},
+			},
+		},
+		{
+			pkg:           "github.com/corsc/fu/bar/",
+			packageFailed: true,
+			output:        "undefined: x\n",
+		},
+	}
+
+	report := buildJUnit(results)
+	assert.Equal(t, 4, report.Tests)
+	assert.Equal(t, 1, report.Failures)
+	assert.Equal(t, 1, report.Errors)
+	assert.Equal(t, 1, report.Skipped)
+	assert.Equal(t, "1.500", report.Time)
+	assert.Len(t, report.Suites, 2)
+
+	suite := report.Suites[0]
+	assert.Equal(t, "github.com/corsc/fu", suite.Name)
+	assert.Equal(t, "FAIL\n", suite.SystemOut)
+	assert.Equal(t, &junitTestCase{
+		ClassName: "github.com/corsc/fu",
+		Name:      "TestA",
+		Time:      "0.250",
+		Failure:   &junitMessage{Message: "Failed", Contents: "boom\n"},
+	}, suite.TestCases[0])
+	assert.Equal(t, "ok\n", suite.TestCases[1].SystemOut)
+	assert.NotNil(t, suite.TestCases[2].Skipped)
+
+	suite = report.Suites[1]
+	assert.Equal(t, 1, suite.Errors)
+	assert.Equal(t, "[package failed]", suite.TestCases[0].Name)
+	assert.Equal(t, "undefined: x\n", suite.TestCases[0].Error.Contents)
+
+	buffer := &bytes.Buffer{}
+	assert.NoError(t, writeJUnit(buffer, report))
+	assert.Contains(t, buffer.String(), `<testsuite name="github.com/corsc/fu" tests="3" failures="1" errors="0" skipped="1" time="1.500">`)
+	assert.Contains(t, buffer.String(), `<failure message="Failed">boom&#xA;</failure>`)
+}
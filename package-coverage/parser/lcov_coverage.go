@@ -0,0 +1,92 @@
+// Copyright 2017 Corey Scott http://www.sage42.org/
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+
+	"github.com/corsc/go-tools/package-coverage/utils"
+)
+
+// LCOVCoverage will output the coverage in the report as an LCOV tracefile.
+// Source files are resolved to their location on disk so that editors and genhtml can find them.
+func LCOVCoverage(writer io.Writer, report *Report) error {
+	return writeLCOV(writer, report.blocks, report.options.Exclusions, report.resolver.resolve)
+}
+
+func writeLCOV(output io.Writer, blocks []block, exclusionsMatcher *regexp.Regexp, resolve func(pkg, file string) string) error {
+	writer := newErrWriter(output)
+
+	blocks = excludeBlocks(blocks, exclusionsMatcher)
+	linesByFile := getLineHitsByFile(blocks)
+
+	filenames := map[string]block{}
+	for _, block := range blocks {
+		filenames[block.filename()] = block
+	}
+
+	sortedFilenames := make([]string, 0, len(filenames))
+	for filename := range filenames {
+		sortedFilenames = append(sortedFilenames, filename)
+	}
+	sort.Strings(sortedFilenames)
+
+	for _, filename := range sortedFilenames {
+		block := filenames[filename]
+		lineHits := linesByFile[filename]
+
+		_, _ = fmt.Fprintf(writer, "TN:\nSF:%s\n", resolve(block.pkg, block.file))
+
+		covered := 0
+		for _, line := range getSortedLines(lineHits) {
+			hits := lineHits[line]
+			if hits > 0 {
+				covered++
+			}
+
+			_, _ = fmt.Fprintf(writer, "DA:%d,%d\n", line, hits)
+		}
+
+		_, _ = fmt.Fprintf(writer, "LF:%d\nLH:%d\nend_of_record\n", len(lineHits), covered)
+	}
+
+	if writer.err != nil {
+		return fmt.Errorf("error writing LCOV output: %w", writer.err)
+	}
+
+	return nil
+}
+
+// remove any blocks whose file matches the exclusions (this is the same matching applied when generating coverage)
+func excludeBlocks(blocks []block, exclusionsMatcher *regexp.Regexp) []block {
+	if exclusionsMatcher == nil {
+		return blocks
+	}
+
+	output := make([]block, 0, len(blocks))
+	for _, block := range blocks {
+		if exclusionsMatcher.MatchString(block.filename()) {
+			utils.LogWhenVerbose("[exclusions] skipped file %s", block.filename())
+			continue
+		}
+
+		output = append(output, block)
+	}
+
+	return output
+}
@@ -0,0 +1,74 @@
+// Copyright 2017 Corey Scott http://www.sage42.org/
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"bytes"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/corsc/go-tools/package-coverage/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteLCOV(t *testing.T) {
+	in := `mode: set
+github.com/corsc/go-tools/package-coverage/main.go:3.10,4.2 1 1
+github.com/corsc/go-tools/package-coverage/main.go:4.2,5.2 2 0
+github.com/corsc/go-tools/package-coverage/main.go:7.2,7.20 0 0
+github.com/corsc/go-tools/package-coverage/_generated/code.go:1.1,2.2 1 1
+`
+	resolve := func(pkg, file string) string {
+		return "/src/" + pkg + file
+	}
+
+	buffer := &bytes.Buffer{}
+	assert.NoError(t, writeLCOV(buffer, parseTestBlocks(t, in), regexp.MustCompile(`/_generated/`), resolve))
+
+	expected := `TN:
+SF:/src/github.com/corsc/go-tools/package-coverage/main.go
+DA:3,1
+DA:4,1
+DA:5,0
+LF:3
+LH:2
+end_of_record
+`
+	assert.Equal(t, expected, buffer.String())
+}
+
+func TestWriteLCOV_WriteError(t *testing.T) {
+	in := `mode: set
+github.com/corsc/go-tools/package-coverage/main.go:3.10,4.2 1 1
+`
+	resolve := func(pkg, file string) string {
+		return "/src/" + pkg + file
+	}
+
+	err := writeLCOV(&failingWriter{}, parseTestBlocks(t, in), nil, resolve)
+	assert.Error(t, err)
+}
+
+func TestSourceResolver(t *testing.T) {
+	resolver := newSourceResolver("./")
+
+	expected := filepath.Join(utils.GetCurrentDir(), "coverage.go")
+	result := resolver.resolve("github.com/corsc/go-tools/package-coverage/parser/", "coverage.go")
+	assert.Equal(t, expected, result)
+
+	result = resolver.resolve("_/tmp/outside/", "file.go")
+	assert.Equal(t, "/tmp/outside/file.go", result)
+}
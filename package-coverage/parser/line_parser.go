@@ -15,77 +15,95 @@
 package parser
 
 import (
-	"log"
-	"strconv"
-	"strings"
+	"bytes"
 )
 
-type fragment struct {
-	pkg        string
-	file       string
-	statements int
-	covered    bool
-}
+const (
+	goSuffix = ".go"
 
-func parseLine(raw string) fragment {
-	output := fragment{
-		pkg:  extractPackage(raw),
-		file: extractFile(raw),
-	}
+	// longer numbers are not valid statement counts (and could overflow)
+	maxNumberLength = 18
+)
 
-	output.statements, output.covered = extractNumbers(raw)
+// profileLine is a single line of a coverage profile in the format
+// "pkg/file.go:startLine.startCol,endLine.endCol statements count"
+type profileLine struct {
+	// filename is the package qualified filename (it refers to the parsed line and is only valid until the next line
+	// is read)
+	filename []byte
 
-	return output
-}
+	startLine int
+	startCol  int
+	endLine   int
+	endCol    int
 
-func extractPackage(raw string) string {
-	lastSlash := strings.LastIndex(raw, "/")
-	if lastSlash == -1 {
-		log.Panicf("line skipped due to lack of package '%s'", raw)
-	}
-
-	return raw[:(lastSlash + 1)]
+	statements int
+	count      int
 }
 
-func extractFile(raw string) string {
-	lastSlash := strings.LastIndex(raw, "/")
-	if lastSlash == -1 {
-		log.Panicf("line skipped due to lack of package '%s'", raw)
+// parseLine parses a line of a coverage profile without allocating.
+// Returns false when the line is not in the expected format (e.g. the mode line or blank lines).
+func parseLine(raw []byte) (profileLine, bool) {
+	output := profileLine{}
+	rest := bytes.TrimSpace(raw)
+
+	// parse from the end of the line as the filename can contain any of the separators
+	var ok bool
+	if rest, output.count, ok = cutNumber(rest, ' '); !ok {
+		return output, false
+	}
+	if rest, output.statements, ok = cutNumber(rest, ' '); !ok {
+		return output, false
+	}
+	if rest, output.endCol, ok = cutNumber(rest, '.'); !ok {
+		return output, false
+	}
+	if rest, output.endLine, ok = cutNumber(rest, ','); !ok {
+		return output, false
+	}
+	if rest, output.startCol, ok = cutNumber(rest, '.'); !ok {
+		return output, false
+	}
+	if rest, output.startLine, ok = cutNumber(rest, ':'); !ok {
+		return output, false
 	}
 
-	fileAndLines := raw[(lastSlash + 1):]
-	line := strings.LastIndex(fileAndLines, ":")
-	if line == -1 {
-		log.Panicf("line skipped due to lack of line number '%s'", raw)
+	// the filename must include the package and a file name
+	firstSlash := bytes.IndexByte(rest, '/')
+	lastSlash := bytes.LastIndexByte(rest, '/')
+	if firstSlash <= 0 || !bytes.HasSuffix(rest, []byte(goSuffix)) || lastSlash+1 >= len(rest)-len(goSuffix) {
+		return output, false
 	}
 
-	return fileAndLines[:line]
+	output.filename = rest
+	return output, true
 }
 
-func extractNumbers(raw string) (int, bool) {
-	parts := strings.Split(raw, " ")
-	if len(parts) != 3 {
-		log.Panicf("invalid line format. parts found %d, expected 3", len(parts))
+// split the supplied line at the last separator and parse the number after it
+func cutNumber(raw []byte, separator byte) ([]byte, int, bool) {
+	index := bytes.LastIndexByte(raw, separator)
+	if index == -1 {
+		return raw, 0, false
 	}
 
-	lines := extractStatements(parts[1])
-	covered := extractCovered(parts[2])
-
-	return lines, covered
+	number, ok := parseNumber(raw[(index + 1):])
+	return raw[:index], number, ok
 }
 
-func extractStatements(raw string) int {
-	statements, err := strconv.Atoi(raw)
-	if err != nil {
-		panic(err)
+// parse a non-negative decimal number (returns false for anything else)
+func parseNumber(raw []byte) (int, bool) {
+	if len(raw) == 0 || len(raw) > maxNumberLength {
+		return 0, false
 	}
-	return statements
-}
 
-func extractCovered(raw string) bool {
-	covered, err := strconv.Atoi(raw)
-	if err != nil {
-		panic(err)
+	number := 0
+	for _, char := range raw {
+		if char < '0' || char > '9' {
+			return 0, false
+		}
+
+		number = number*10 + int(char-'0')
 	}
-	return covered > 0
+
+	return number, true
 }
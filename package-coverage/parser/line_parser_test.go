@@ -21,53 +21,30 @@ import (
 )
 
 func TestParseLine(t *testing.T) {
-	in := "github.com/corsc/go-tools/package-coverage/line_parser.go:9.37,11.2 1 0"
-	expected := fragment{
-		pkg:        "github.com/corsc/go-tools/package-coverage/",
-		file:       "line_parser.go",
+	in := "github.com/corsc/go-tools/package-coverage/line_parser.go:9.37,11.2 1 3"
+	expected := profileLine{
+		filename:   []byte("github.com/corsc/go-tools/package-coverage/line_parser.go"),
+		startLine:  9,
+		startCol:   37,
+		endLine:    11,
+		endCol:     2,
 		statements: 1,
-		covered:    false,
+		count:      3,
 	}
 
-	result := parseLine(in)
+	result, ok := parseLine([]byte(in))
+	assert.True(t, ok)
 	assert.Equal(t, expected, result)
 }
 
-func TestExtractPackage_HappyPath(t *testing.T) {
-	in := "github.com/corsc/go-tools/package-coverage/line_parser.go:9.37,11.2 1 0"
-	expected := "github.com/corsc/go-tools/package-coverage/"
-
-	result := extractPackage(in)
-	assert.Equal(t, expected, result)
-}
-
-func TestExtractPackage_InvalidLinePanic(t *testing.T) {
+func TestParseLine_InvalidLine(t *testing.T) {
 	in := ""
 
-	assert.Panics(t, func() {
-		extractPackage(in)
-	})
-}
-
-func TestExtractNumbers_HappyPath(t *testing.T) {
-	in := "github.com/corsc/go-tools/package-coverage/line_parser.go:9.37,11.2 1 0"
-	expectedStatements := 1
-	expectedCovered := false
-
-	resultLines, resultCoverted := extractNumbers(in)
-	assert.Equal(t, expectedStatements, resultLines)
-	assert.Equal(t, expectedCovered, resultCoverted)
+	_, ok := parseLine([]byte(in))
+	assert.False(t, ok)
 }
 
-func TestExtractNumbers_InvalidLinePanics(t *testing.T) {
-	in := ""
-
-	assert.Panics(t, func() {
-		extractNumbers(in)
-	})
-}
-
-func TestExtractStatements_HappyPath(t *testing.T) {
+func TestParseNumber_HappyPath(t *testing.T) {
 	scenarios := []struct {
 		in       string
 		expected int
@@ -83,36 +60,17 @@ func TestExtractStatements_HappyPath(t *testing.T) {
 	}
 
 	for _, scenario := range scenarios {
-		result := extractStatements(scenario.in)
+		result, ok := parseNumber([]byte(scenario.in))
+		assert.True(t, ok)
 		assert.Equal(t, scenario.expected, result)
 	}
 }
 
-func TestExtractStatements_InvalidInputPanics(t *testing.T) {
-	in := ""
-
-	assert.Panics(t, func() {
-		extractStatements(in)
-	})
-}
-
-func TestExtractCovered_HappyPath(t *testing.T) {
-	scenarios := []struct {
-		in       string
-		expected bool
-	}{
-		{
-			in:       "0",
-			expected: false,
-		},
-		{
-			in:       "1",
-			expected: true,
-		},
-	}
+func TestParseNumber_InvalidInput(t *testing.T) {
+	scenarios := []string{"", "-1", "1.5", "12a", "1234567890123456789"}
 
 	for _, scenario := range scenarios {
-		result := extractCovered(scenario.in)
-		assert.Equal(t, scenario.expected, result)
+		_, ok := parseNumber([]byte(scenario))
+		assert.False(t, ok, scenario)
 	}
 }
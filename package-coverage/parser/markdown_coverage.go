@@ -0,0 +1,199 @@
+// Copyright 2017 Corey Scott http://www.sage42.org/
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	markdownTitle        = "## Test Coverage"
+	markdownHeader       = "| | Package | Coverage | Covered | Statements |\n|:-:|:--|--:|--:|--:|\n"
+	markdownLine         = "| %s | `%s` | %.2f%% | %d | %d |\n"
+	markdownDeltaHeader  = "| | Package | Coverage | Δ | Covered | Statements |\n|:-:|:--|--:|--:|--:|--:|\n"
+	markdownDeltaLine    = "| %s | `%s` | %.2f%% | %s | %d | %d |\n"
+	markdownStatusOk     = "✅"
+	markdownStatusFailed = "❌"
+
+	markdownTimedOutLine      = "| %s | `%s` | timed out | | |\n"
+	markdownDeltaTimedOutLine = "| %s | `%s` | timed out | | | |\n"
+	markdownStatusTimedOut    = "⏱️"
+)
+
+// MarkdownCoverage will output the coverage in the report as a (GitHub/GitLab flavoured) Markdown summary suitable
+// for posting as a pull request comment.
+// Each depth level is a collapsible section.  When a baseline profile is supplied, the change of each package is
+// included.
+func MarkdownCoverage(writer io.Writer, report *Report, baselineProfile string, minCoverage int, prefix string, depth int) error {
+	baselineData, err := loadBaselineProfile(baselineProfile, report.options.Exclusions)
+	if err != nil {
+		return err
+	}
+
+	writeMarkdown(writer, report.pkgs, report.coverageData, baselineData, float64(minCoverage), newPrefixer(prefix, report.Modules), depth)
+	return nil
+}
+
+// load the coverage of each package from the supplied profile (returns nil when there is no baseline)
+func loadBaselineProfile(filename string, exclusionsMatcher *regexp.Regexp) (coverageByPackage, error) {
+	if filename == "" {
+		return nil, nil
+	}
+
+	baseline, err := loadProfileFile(filename, exclusionsMatcher)
+	if err != nil {
+		return nil, err
+	}
+
+	return baseline.getCoverage(), nil
+}
+
+// markdownLevel is the packages at a single depth
+type markdownLevel struct {
+	depth int
+	lines []string
+}
+
+func writeMarkdown(writer io.Writer, pkgs []string, coverageData, baselineData coverageByPackage, minCoverage float64, prefix *prefixer, depth int) {
+	_, _ = fmt.Fprintf(writer, "%s\n\n", markdownTitle)
+
+	total, totalCovered, totalStmts := getTotalValues(pkgs, coverageData)
+	_, _ = fmt.Fprintf(writer, "%s **Total: %.2f%%** (%d of %d statements)", getMarkdownStatus(total, minCoverage), total, totalCovered, totalStmts)
+	if baselineData != nil {
+		baselineTotal, _, _ := getTotalValues(getSortedPackages(baselineData), baselineData)
+		_, _ = fmt.Fprintf(writer, " %s", formatMarkdownDelta(total-baselineTotal))
+	}
+	_, _ = fmt.Fprint(writer, "\n\n")
+
+	levels := map[int]*markdownLevel{}
+	failed := 0
+	timedOut := 0
+
+	forEachPackage(pkgs, prefix, depth, func(pkg string, pkgFormatted string) {
+		pkgDepth := strings.Count(pkgFormatted, "/")
+
+		level, found := levels[pkgDepth]
+		if !found {
+			level = &markdownLevel{depth: pkgDepth}
+			levels[pkgDepth] = level
+		}
+
+		cover := coverageData[pkg]
+		covered, stmtsCovered, stmts := getSummaryValues(cover)
+		if cover.timedOut {
+			timedOut++
+		} else if covered < minCoverage {
+			failed++
+		}
+
+		status := getMarkdownStatus(covered, minCoverage)
+
+		var line string
+		if cover.timedOut && baselineData == nil {
+			line = fmt.Sprintf(markdownTimedOutLine, markdownStatusTimedOut, pkgFormatted)
+		} else if cover.timedOut {
+			line = fmt.Sprintf(markdownDeltaTimedOutLine, markdownStatusTimedOut, pkgFormatted)
+		} else if baselineData == nil {
+			line = fmt.Sprintf(markdownLine, status, pkgFormatted, covered, int(stmtsCovered), int(stmts))
+		} else {
+			line = fmt.Sprintf(markdownDeltaLine, status, pkgFormatted, covered, getMarkdownDelta(pkg, covered, baselineData), int(stmtsCovered), int(stmts))
+		}
+
+		level.lines = append(level.lines, line)
+	})
+
+	if failed > 0 {
+		_, _ = fmt.Fprintf(writer, "%s %d package(s) below the minimum coverage of %.0f%%\n\n", markdownStatusFailed, failed, minCoverage)
+	}
+
+	if timedOut > 0 {
+		_, _ = fmt.Fprintf(writer, "%s %d package(s) timed out; their coverage is incomplete\n\n", markdownStatusTimedOut, timedOut)
+	}
+
+	header := markdownHeader
+	if baselineData != nil {
+		header = markdownDeltaHeader
+	}
+
+	// the shallowest level is expanded
+	for index, level := range sortMarkdownLevels(levels) {
+		open := ""
+		if index == 0 {
+			open = " open"
+		}
+
+		_, _ = fmt.Fprintf(writer, "<details%s>\n<summary>Depth %d (%d packages)</summary>\n\n", open, level.depth, len(level.lines))
+		_, _ = fmt.Fprint(writer, header)
+		_, _ = fmt.Fprint(writer, strings.Join(level.lines, ""))
+		_, _ = fmt.Fprint(writer, "\n</details>\n\n")
+	}
+}
+
+// returns the total coverage of the supplied packages (without double counting children)
+func getTotalValues(pkgs []string, coverageData coverageByPackage) (float64, int, int) {
+	covered := 0
+	stmts := 0
+
+	for _, pkg := range pkgs {
+		covered += coverageData[pkg].selfCovered
+		stmts += coverageData[pkg].selfStatements
+	}
+
+	return getPercentage(float64(stmts), float64(covered)), covered, stmts
+}
+
+func sortMarkdownLevels(levels map[int]*markdownLevel) []*markdownLevel {
+	output := make([]*markdownLevel, 0, len(levels))
+	for _, level := range levels {
+		output = append(output, level)
+	}
+
+	sort.Slice(output, func(i, j int) bool {
+		return output[i].depth < output[j].depth
+	})
+
+	return output
+}
+
+func getMarkdownStatus(covered float64, minCoverage float64) string {
+	if covered < minCoverage {
+		return markdownStatusFailed
+	}
+	return markdownStatusOk
+}
+
+// returns the change of the package compared to the baseline ("new" when the package is not in the baseline)
+func getMarkdownDelta(pkg string, covered float64, baselineData coverageByPackage) string {
+	previous, found := baselineData[pkg]
+	if !found {
+		return "new"
+	}
+
+	previousCovered, _, _ := getSummaryValues(previous)
+	return formatMarkdownDelta(covered - previousCovered)
+}
+
+func formatMarkdownDelta(delta float64) string {
+	// avoid "-0.00" and "+0.00"
+	if delta > -0.005 && delta < 0.005 {
+		return "±0.00%"
+	}
+
+	return fmt.Sprintf("%+.2f%%", delta)
+}
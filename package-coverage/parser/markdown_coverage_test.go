@@ -0,0 +1,89 @@
+// Copyright 2017 Corey Scott http://www.sage42.org/
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteMarkdown(t *testing.T) {
+	pkgs, coverageData := getTestCoverage(t, `mode: set
+github.com/corsc/fu/a.go:3.24,4.12 1 1
+github.com/corsc/fu/a.go:4.12,6.3 1 0
+github.com/corsc/fu/bar/b.go:1.1,2.2 2 1
+github.com/corsc/fu/bar/baz/c.go:1.1,2.2 4 0
+`)
+
+	buffer := &bytes.Buffer{}
+	writeMarkdown(buffer, pkgs, coverageData, nil, 50, newPrefixer("github.com/corsc/", nil), 2)
+
+	expected := "## Test Coverage\n" +
+		"\n" +
+		"❌ **Total: 37.50%** (3 of 8 statements)\n" +
+		"\n" +
+		"❌ 2 package(s) below the minimum coverage of 50%\n" +
+		"\n" +
+		"<details open>\n" +
+		"<summary>Depth 1 (1 packages)</summary>\n" +
+		"\n" +
+		"| | Package | Coverage | Covered | Statements |\n" +
+		"|:-:|:--|--:|--:|--:|\n" +
+		"| ❌ | `fu/` | 37.50% | 3 | 8 |\n" +
+		"\n" +
+		"</details>\n" +
+		"\n" +
+		"<details>\n" +
+		"<summary>Depth 2 (1 packages)</summary>\n" +
+		"\n" +
+		"| | Package | Coverage | Covered | Statements |\n" +
+		"|:-:|:--|--:|--:|--:|\n" +
+		"| ❌ | `fu/bar/` | 33.33% | 2 | 6 |\n" +
+		"\n" +
+		"</details>\n" +
+		"\n"
+	assert.Equal(t, expected, buffer.String())
+}
+
+func TestWriteMarkdownWithBaseline(t *testing.T) {
+	pkgs, coverageData := getTestCoverage(t, `mode: set
+github.com/corsc/fu/a.go:3.24,4.12 1 1
+github.com/corsc/fu/a.go:4.12,6.3 1 1
+github.com/corsc/fu/bar/b.go:1.1,2.2 2 1
+`)
+
+	baselineData := parseTestProfile(t, `mode: set
+github.com/corsc/fu/a.go:3.24,4.12 1 1
+github.com/corsc/fu/a.go:4.12,6.3 1 0
+`).getCoverage()
+
+	buffer := &bytes.Buffer{}
+	writeMarkdown(buffer, pkgs, coverageData, baselineData, 0, newPrefixer("github.com/corsc/", nil), 0)
+
+	output := buffer.String()
+	assert.Contains(t, output, "✅ **Total: 100.00%** (4 of 4 statements) +50.00%\n")
+	assert.Contains(t, output, "| | Package | Coverage | Δ | Covered | Statements |\n")
+	assert.Contains(t, output, "| ✅ | `fu/` | 100.00% | +50.00% | 4 | 4 |\n")
+	assert.Contains(t, output, "| ✅ | `fu/bar/` | 100.00% | new | 2 | 2 |\n")
+	assert.NotContains(t, output, "below the minimum")
+}
+
+func TestFormatMarkdownDelta(t *testing.T) {
+	assert.Equal(t, "±0.00%", formatMarkdownDelta(-0.001))
+	assert.Equal(t, "-1.25%", formatMarkdownDelta(-1.25))
+	assert.Equal(t, "+0.50%", formatMarkdownDelta(0.5))
+}
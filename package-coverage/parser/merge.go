@@ -0,0 +1,187 @@
+// Copyright 2017 Corey Scott http://www.sage42.org/
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/corsc/go-tools/package-coverage/utils"
+)
+
+const (
+	modePrefix = "mode: "
+	modeSet    = "set"
+)
+
+// find the coverage profiles matching the supplied patterns (see filepath.Glob); returns their absolute paths
+func findProfiles(patterns []string) ([]string, error) {
+	var output []string
+
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid coverage profile pattern '%s': %w", pattern, err)
+		}
+
+		if len(matches) == 0 {
+			utils.LogAlways("[merge] no coverage profiles found matching '%s'", pattern)
+		}
+
+		for _, match := range matches {
+			absMatch, err := filepath.Abs(match)
+			if err != nil {
+				return nil, err
+			}
+
+			output = append(output, absMatch)
+		}
+	}
+
+	return output, nil
+}
+
+// read the external profiles (matching the supplied patterns) that are not already included in the supplied paths into
+// the supplied profile (skipping the blocks of any files that match the profile's exclusions)
+func readExternalProfiles(ctx context.Context, output *profile, patterns []string, paths []string) error {
+	externals, err := findProfiles(patterns)
+	if err != nil {
+		return err
+	}
+
+	included := map[string]struct{}{}
+	for _, path := range paths {
+		included[filepath.Clean(path)] = struct{}{}
+	}
+
+	for _, external := range externals {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if _, found := included[external]; found {
+			continue
+		}
+
+		utils.LogWhenVerbose("[merge] merging coverage profile '%s'", external)
+
+		err := output.readFile(external, external, true)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// addBlock merges the supplied line into the blocks of the profile so that each block (keyed by file and range)
+// appears only once.  The same block is found in multiple profiles when the tests of one package cover others
+// (e.g. with -coverpkg) or when profiles from other runs are merged.
+// The counts are summed; in set mode a block is covered when it was covered in any of the profiles (see getCount).
+// Returns the position of the block in the blocks of the profile.
+func (p *profile) addBlock(file *profileFile, line profileLine) int {
+	key := blockKey{
+		file:      file,
+		startLine: line.startLine,
+		startCol:  line.startCol,
+		endLine:   line.endLine,
+		endCol:    line.endCol,
+	}
+
+	if index, found := p.index[key]; found {
+		p.blocks[index].count += line.count
+		return index
+	}
+
+	index := len(p.blocks)
+	p.index[key] = index
+	p.blocks = append(p.blocks, block{
+		pkg:        file.pkg,
+		file:       file.file,
+		startLine:  line.startLine,
+		startCol:   line.startCol,
+		endLine:    line.endLine,
+		endCol:     line.endCol,
+		statements: line.statements,
+		count:      line.count,
+	})
+
+	if p.attribution {
+		p.coveredBy = append(p.coveredBy, nil)
+	}
+
+	return index
+}
+
+// addCoveredBy records that the block at the supplied position was covered by the supplied source
+func (p *profile) addCoveredBy(index int, source string) {
+	for _, existing := range p.coveredBy[index] {
+		if existing == source {
+			return
+		}
+	}
+
+	p.coveredBy[index] = append(p.coveredBy[index], source)
+}
+
+// addMode records the mode of one of the merged profiles.
+// When the profiles have different modes, the counts are not comparable and therefore set mode is used.
+func (p *profile) addMode(mode string) {
+	if p.mode == "" {
+		p.mode = mode
+		return
+	}
+
+	// count and atomic mode both contain the number of times each block was executed
+	if (mode == modeSet) != (p.mode == modeSet) && !p.mixed {
+		utils.LogAlways("[merge] merging coverage profiles with modes '%s' and '%s'; using set mode", p.mode, mode)
+		p.mixed = true
+	}
+}
+
+// returns the mode of the merged profile (set when the profiles mix set mode with count or atomic mode)
+func (p *profile) getMode() string {
+	if p.mixed {
+		return modeSet
+	}
+
+	return p.mode
+}
+
+// returns the merged count of a block for the mode of the profile
+func (p *profile) getCount(count int) int {
+	if p.getMode() == modeSet && count > 0 {
+		return 1
+	}
+
+	return count
+}
+
+// String returns the merged profile in the coverage profile format
+func (p *profile) String() string {
+	output := &strings.Builder{}
+	if mode := p.getMode(); mode != "" {
+		output.WriteString(modePrefix + mode + "\n")
+	}
+
+	for _, thisBlock := range p.blocks {
+		_, _ = fmt.Fprintf(output, "%s:%d.%d,%d.%d %d %d\n", thisBlock.filename(), thisBlock.startLine, thisBlock.startCol,
+			thisBlock.endLine, thisBlock.endCol, thisBlock.statements, p.getCount(thisBlock.count))
+	}
+
+	return output.String()
+}
@@ -0,0 +1,132 @@
+// Copyright 2017 Corey Scott http://www.sage42.org/
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergeProfiles(t *testing.T) {
+	scenarios := []struct {
+		desc     string
+		in       string
+		expected string
+	}{
+		{
+			desc: "set mode",
+			in: `mode: set
+github.com/corsc/fu/a.go:3.24,4.12 1 0
+github.com/corsc/fu/a.go:4.12,6.3 1 1
+mode: set
+github.com/corsc/fu/a.go:3.24,4.12 1 1
+github.com/corsc/fu/a.go:4.12,6.3 1 1
+github.com/corsc/fu/b.go:1.1,2.2 2 0
+`,
+			expected: `mode: set
+github.com/corsc/fu/a.go:3.24,4.12 1 1
+github.com/corsc/fu/a.go:4.12,6.3 1 1
+github.com/corsc/fu/b.go:1.1,2.2 2 0
+`,
+		},
+		{
+			desc: "count mode",
+			in: `mode: count
+github.com/corsc/fu/a.go:3.24,4.12 1 2
+mode: count
+github.com/corsc/fu/a.go:3.24,4.12 1 3
+`,
+			expected: `mode: count
+github.com/corsc/fu/a.go:3.24,4.12 1 5
+`,
+		},
+		{
+			desc: "mixed modes",
+			in: `mode: count
+github.com/corsc/fu/a.go:3.24,4.12 1 2
+github.com/corsc/fu/a.go:4.12,6.3 1 0
+mode: set
+github.com/corsc/fu/a.go:3.24,4.12 1 0
+github.com/corsc/fu/a.go:4.12,6.3 1 1
+`,
+			expected: `mode: set
+github.com/corsc/fu/a.go:3.24,4.12 1 1
+github.com/corsc/fu/a.go:4.12,6.3 1 1
+`,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		assert.Equal(t, scenario.expected, parseTestProfile(t, scenario.in).String(), scenario.desc)
+	}
+}
+
+func TestReadExternalProfiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "merge")
+	assert.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+
+	generated := filepath.Join(dir, "profile.cov")
+	integration := filepath.Join(dir, "integration.cov")
+
+	assert.NoError(t, ioutil.WriteFile(generated, []byte("mode: set\ngithub.com/corsc/fu/a.go:3.24,4.12 1 0\n"), 0600))
+	assert.NoError(t, ioutil.WriteFile(integration, []byte("mode: set\ngithub.com/corsc/fu/a.go:3.24,4.12 1 1\ngithub.com/corsc/fu/z_generated.go:1.1,2.2 1 1"), 0600))
+
+	patterns := []string{filepath.Join(dir, "*.cov")}
+
+	externals, err := findProfiles(patterns)
+	assert.NoError(t, err)
+	assert.Len(t, externals, 2)
+
+	// the generated profile is already included and excluded files are removed
+	external := newProfile(regexp.MustCompile(`/z_.*`))
+	err = readExternalProfiles(context.Background(), external, patterns, []string{generated})
+	assert.NoError(t, err)
+	assert.Equal(t, "mode: set\ngithub.com/corsc/fu/a.go:3.24,4.12 1 1\n", external.String())
+
+	merged, err := readProfiles(context.Background(), "", []string{generated}, LoadOptions{Exclusions: regexp.MustCompile(`/z_.*`), Profiles: patterns})
+	assert.NoError(t, err)
+	assert.Equal(t, "mode: set\ngithub.com/corsc/fu/a.go:3.24,4.12 1 1\n", merged.String())
+}
+
+func TestLoad_ProfilesAreOnlyMergedIntoTheirReport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "merge")
+	assert.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+
+	basePath := filepath.Join(dir, "src")
+	assert.NoError(t, os.Mkdir(basePath, 0700))
+
+	writeTestOutput(t, basePath, "", "profile.cov", "mode: set\ngithub.com/corsc/fu/a.go:3.24,4.12 1 0\n")
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "integration.cov"), []byte("mode: set\ngithub.com/corsc/fu/a.go:3.24,4.12 1 1\n"), 0600))
+
+	merged, err := Load(context.Background(), LoadOptions{BasePath: basePath, Profiles: []string{filepath.Join(dir, "*.cov")}})
+	assert.NoError(t, err)
+	assert.Equal(t, Counts{Statements: 1, Covered: 1}, merged.Total())
+
+	generated, err := Load(context.Background(), LoadOptions{BasePath: basePath})
+	assert.NoError(t, err)
+	assert.Equal(t, Counts{Statements: 1, Covered: 0}, generated.Total())
+}
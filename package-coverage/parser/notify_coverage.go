@@ -0,0 +1,60 @@
+// Copyright 2017 Corey Scott http://www.sage42.org/
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"context"
+
+	"github.com/corsc/go-tools/package-coverage/notifier"
+)
+
+const notifyTitle = "Test Coverage"
+
+// NotifyCoverage will send the coverage of each package in the report (within the depth) using the notifier
+func NotifyCoverage(ctx context.Context, target notifier.Notifier, report *Report, thresholds notifier.Thresholds, prefix string, depth int) error {
+	return target.Notify(ctx, buildNotification(report, thresholds, newPrefixer(prefix, report.Modules), depth))
+}
+
+// build the notification of the coverage (including children) of each package within the depth
+func buildNotification(report *Report, thresholds notifier.Thresholds, prefix *prefixer, depth int) *notifier.Report {
+	notification := &notifier.Report{
+		Title: notifyTitle,
+	}
+
+	for _, pkg := range report.Packages {
+		pkgFormatted := prefix.trim(pkg.Path)
+		if !withinDepth(pkgFormatted, depth) {
+			continue
+		}
+
+		branch := pkg.Branch()
+
+		level := thresholds.Level(branch.Percent())
+		if pkg.TimedOut {
+			level = notifier.LevelDanger
+		}
+
+		notification.Packages = append(notification.Packages, notifier.Package{
+			Name:       pkgFormatted,
+			Coverage:   branch.Percent(),
+			Covered:    branch.Covered,
+			Statements: branch.Statements,
+			Level:      level,
+			TimedOut:   pkg.TimedOut,
+		})
+	}
+
+	return notification
+}
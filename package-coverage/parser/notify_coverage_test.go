@@ -0,0 +1,42 @@
+// Copyright 2017 Corey Scott http://www.sage42.org/
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"testing"
+
+	"github.com/corsc/go-tools/package-coverage/notifier"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildNotification(t *testing.T) {
+	pkgs, coverageData := getTestCoverage(t, `mode: set
+github.com/corsc/fu/a.go:3.24,4.12 1 1
+github.com/corsc/fu/a.go:4.12,6.3 1 0
+github.com/corsc/fu/bar/b.go:1.1,2.2 2 1
+github.com/corsc/fu/bar/baz/c.go:1.1,2.2 4 0
+`)
+
+	result := buildNotification(newReport(pkgs, coverageData, nil), notifier.Thresholds{Good: 35, Warning: 30}, newPrefixer("github.com/corsc/", nil), 2)
+	assert.Equal(t, "Test Coverage", result.Title)
+	assert.Len(t, result.Packages, 2)
+
+	assert.Equal(t, notifier.Package{Name: "fu/", Coverage: 37.5, Covered: 3, Statements: 8, Level: notifier.LevelGood}, result.Packages[0])
+
+	assert.Equal(t, "fu/bar/", result.Packages[1].Name)
+	assert.InDelta(t, 33.33, result.Packages[1].Coverage, 0.01)
+	assert.Equal(t, 6, result.Packages[1].Statements)
+	assert.Equal(t, notifier.LevelWarning, result.Packages[1].Level)
+}
@@ -0,0 +1,115 @@
+// Copyright 2017 Corey Scott http://www.sage42.org/
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/corsc/go-tools/package-coverage/config"
+)
+
+// stdOutFilename is the output filename that signals output should be sent to StdOut instead of a file
+const stdOutFilename = "-"
+
+// open the supplied output file for writing (or StdOut when the filename is "-")
+func createOutput(filename string) (io.WriteCloser, error) {
+	if filename == stdOutFilename {
+		return nopWriteCloser{Writer: os.Stdout}, nil
+	}
+
+	file, err := os.Create(filename)
+	if err != nil {
+		return nil, fmt.Errorf("error creating output file '%s': %w", filename, err)
+	}
+
+	return file, nil
+}
+
+// close the supplied output and (when there is no other error) set the supplied error when the contents could not be
+// flushed
+func closeOutput(filename string, output io.Closer, resultErr *error) {
+	err := output.Close()
+	if err != nil && *resultErr == nil {
+		*resultErr = fmt.Errorf("error closing output file '%s': %w", filename, err)
+	}
+}
+
+// returns a filesystem safe (and reversible) filename for the supplied package or file.
+// Slashes become underscores and any other character that is not a letter, digit or dot (including underscores and
+// dashes) is escaped as a dash and its hex code so that different packages and files never share a filename.
+func escapeFilename(name string) string {
+	output := &strings.Builder{}
+
+	for _, char := range []byte(strings.Trim(name, "/")) {
+		switch {
+		case char == '/':
+			output.WriteByte('_')
+
+		case char == '.' || (char >= 'a' && char <= 'z') || (char >= 'A' && char <= 'Z') || (char >= '0' && char <= '9'):
+			output.WriteByte(char)
+
+		default:
+			_, _ = fmt.Fprintf(output, "-%02x", char)
+		}
+	}
+
+	return output.String()
+}
+
+// returns where the console output should be written (StdErr when StdOut contains machine-readable output)
+func getConsole(cfg *config.Config) io.Writer {
+	if cfg.MachineOutput {
+		return os.Stderr
+	}
+
+	return os.Stdout
+}
+
+// errWriter keeps the first error returned by the writer (and skips any later writes) so that outputs written with
+// many small writes only need to check for an error once they are done
+type errWriter struct {
+	writer io.Writer
+	err    error
+}
+
+func newErrWriter(writer io.Writer) *errWriter {
+	return &errWriter{
+		writer: writer,
+	}
+}
+
+func (w *errWriter) Write(data []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+
+	written, err := w.writer.Write(data)
+	if err != nil {
+		w.err = err
+	}
+
+	return written, err
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
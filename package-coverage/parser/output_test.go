@@ -0,0 +1,54 @@
+// Copyright 2017 Corey Scott http://www.sage42.org/
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrWriter(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	writer := newErrWriter(buffer)
+
+	_, _ = fmt.Fprint(writer, "fu")
+	assert.NoError(t, writer.err)
+	assert.Equal(t, "fu", buffer.String())
+
+	// the first error is kept and later writes are skipped
+	failing := &failingWriter{}
+	writer = newErrWriter(failing)
+
+	_, _ = fmt.Fprint(writer, "fu")
+	_, _ = fmt.Fprint(writer, "bar")
+	assert.Equal(t, errTestWrite, writer.err)
+	assert.Equal(t, 1, failing.calls)
+}
+
+var errTestWrite = errors.New("write failed")
+
+// failingWriter fails every write
+type failingWriter struct {
+	calls int
+}
+
+func (w *failingWriter) Write([]byte) (int, error) {
+	w.calls++
+	return 0, errTestWrite
+}
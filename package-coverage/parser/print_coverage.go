@@ -17,52 +17,53 @@ package parser
 import (
 	"fmt"
 	"io"
-	"log"
-	"regexp"
 	"strings"
 
 	"github.com/corsc/go-tools/package-coverage/utils"
 )
 
 const (
-	header1Template   = "| %-24s | %-24s | %-80s |\n"
-	header2Template   = "| %6s | %6s | %6s | %6s | %6s | %6s | %-80s |\n"
-	lineTemplate      = "| %6.2f | %6.0f | %6.0f | %6.2f | %6.0f | %6.0f | %-80s |\n"
-	errHighlightStart = "\033[1;31m"
-	errHighlightEnd   = "\033[0m"
+	header1Template     = "| %-24s | %-24s | %-80s |\n"
+	header2Template     = "| %6s | %6s | %6s | %6s | %6s | %6s | %-80s |\n"
+	lineTemplate        = "| %6.2f | %6.0f | %6.0f | %6.2f | %6.0f | %6.0f | %-80s |\n"
+	shortHeaderTemplate = "| %6s | %6s | %6s | %-107s |\n"
+	shortLineTemplate   = "| %6.2f | %6d | %6d | %-107s |\n"
+	errHighlightStart   = "\033[1;31m"
+	errHighlightEnd     = "\033[0m"
 )
 
 // CoverageByPackage contains the result of parsing one or more package's coverage file
 type coverageByPackage map[string]*coverage
 
-// PrintCoverage will attempt to calculate and print the coverage from the supplied coverage file to standard out.
-func PrintCoverage(writer io.Writer, basePath string, exclusionsMatcher *regexp.Regexp, minCoverage int, prefix string, depth int) bool {
-	paths, err := utils.FindAllCoverageFiles(basePath)
-	if err != nil {
-		log.Panicf("error file finding coverage files %s", err)
+// PrintCoverage will print the coverage of each package in the report.
+// When the report contains multiple modules, the coverage is grouped by module.
+func PrintCoverage(writer io.Writer, report *Report, minCoverage int, prefix string, depth int) bool {
+	if len(report.Modules) > 1 {
+		return printCoverageByModule(writer, report.Modules, report.Packages, float64(minCoverage), newPrefixer(prefix, report.Modules), depth)
 	}
 
-	pkgs, coverageData := getCoverageData(paths, exclusionsMatcher)
-	return printCoverage(writer, pkgs, coverageData, float64(minCoverage), prefix, depth)
+	return printCoverage(writer, report.Packages, float64(minCoverage), newPrefixer(prefix, report.Modules), depth)
 }
 
-// PrintCoverageSingle is the same as PrintCoverage only for 1 directory only
-func PrintCoverageSingle(writer io.Writer, path string, minCoverage int, prefix string, depth int) bool {
-	var fullPath string
-	if path == "./" {
-		fullPath = utils.GetCurrentDir()
-	} else {
-		fullPath = utils.GetCurrentDir() + path + "/"
-	}
-	fullPath += "profile.cov"
+// CheckCoverage returns false when the coverage of any package in the report (within the depth) is below the minimum
+// or timed out (i.e. the packages PrintCoverage highlights)
+func CheckCoverage(report *Report, minCoverage int, prefix string, depth int) bool {
+	names := newPrefixer(prefix, report.Modules)
 
-	contents := getFileContents(fullPath)
-	pkgs, coverageData := getCoverageByContents(contents)
+	for _, pkg := range report.Packages {
+		if !withinDepth(names.trim(pkg.Path), depth) {
+			continue
+		}
 
-	return printCoverage(writer, pkgs, coverageData, float64(minCoverage), prefix, depth)
+		if pkg.TimedOut || pkg.Branch().Percent() < float64(minCoverage) {
+			return false
+		}
+	}
+
+	return true
 }
 
-func printCoverage(writer io.Writer, pkgs []string, coverageData coverageByPackage, minCoverage float64, prefix string, depth int) bool {
+func printCoverage(writer io.Writer, pkgs []*Package, minCoverage float64, prefix *prefixer, depth int) bool {
 	addLine(writer)
 	_, _ = fmt.Fprintf(writer, header1Template, "Branch", "Dir", "")
 	_, _ = fmt.Fprintf(writer, header2Template, "Cov%", "Cov", "Stmts", "Cov%", "Cov", "Stmts", "Package")
@@ -70,21 +71,13 @@ func printCoverage(writer io.Writer, pkgs []string, coverageData coverageByPacka
 
 	coverageOk := true
 	for _, pkg := range pkgs {
-		cover := coverageData[pkg]
-
-		pkgFormatted := strings.Replace(pkg, prefix, "", -1)
-		pkgDepth := strings.Count(pkgFormatted, "/")
-
-		if depth > 0 {
-			if pkgDepth <= depth {
-				if !addLinePrint(writer, pkgFormatted, cover, minCoverage) {
-					coverageOk = false
-				}
-			}
-		} else {
-			if !addLinePrint(writer, pkgFormatted, cover, minCoverage) {
-				coverageOk = false
-			}
+		pkgFormatted := prefix.trim(pkg.Path)
+		if !withinDepth(pkgFormatted, depth) {
+			continue
+		}
+
+		if !addLinePrint(writer, pkgFormatted, pkg, minCoverage) {
+			coverageOk = false
 		}
 	}
 	addLine(writer)
@@ -92,6 +85,103 @@ func printCoverage(writer io.Writer, pkgs []string, coverageData coverageByPacka
 	return coverageOk
 }
 
+// print the coverage of each module (with the module's total)
+func printCoverageByModule(writer io.Writer, modules []*utils.Module, pkgs []*Package, minCoverage float64, prefix *prefixer, depth int) bool {
+	pkgsByModule := map[*utils.Module][]*Package{}
+	var otherPkgs []*Package
+
+	for _, pkg := range pkgs {
+		module := utils.FindModuleForPackage(modules, strings.TrimSuffix(pkg.Path, "/"))
+		if module == nil {
+			otherPkgs = append(otherPkgs, pkg)
+			continue
+		}
+
+		pkgsByModule[module] = append(pkgsByModule[module], pkg)
+	}
+
+	coverageOk := true
+	for _, module := range modules {
+		modulePkgs := pkgsByModule[module]
+		if len(modulePkgs) == 0 {
+			continue
+		}
+
+		_, _ = fmt.Fprintf(writer, "Module %s\n", module.Path)
+		if !printCoverage(writer, modulePkgs, minCoverage, prefix, depth) {
+			coverageOk = false
+		}
+
+		total := &Package{}
+		for _, pkg := range modulePkgs {
+			total.Self.Statements += pkg.Self.Statements
+			total.Self.Covered += pkg.Self.Covered
+		}
+
+		addLinePrint(writer, "Total", total, minCoverage)
+		addLine(writer)
+	}
+
+	if len(otherPkgs) > 0 {
+		_, _ = fmt.Fprint(writer, "Other\n")
+		if !printCoverage(writer, otherPkgs, minCoverage, prefix, depth) {
+			coverageOk = false
+		}
+	}
+
+	return coverageOk
+}
+
+// prefixer removes the prefix from package names.
+// When no prefix was supplied and there are multiple modules, the prefix is derived from the module of each package
+// (see utils.GetModulePrefix) so that the depth applies within each module.
+type prefixer struct {
+	prefix  string
+	modules []*utils.Module
+}
+
+func newPrefixer(prefix string, modules []*utils.Module) *prefixer {
+	return &prefixer{
+		prefix:  prefix,
+		modules: modules,
+	}
+}
+
+// returns the package (or filename) with the prefix removed
+func (p *prefixer) trim(pkg string) string {
+	if p.prefix != "" || len(p.modules) < 2 {
+		return strings.Replace(pkg, p.prefix, "", -1)
+	}
+
+	module := utils.FindModuleForPackage(p.modules, strings.TrimSuffix(pkg, "/"))
+	if module == nil {
+		return pkg
+	}
+
+	return strings.TrimPrefix(pkg, utils.GetModulePrefix(module))
+}
+
+// call fn with each package (and the package with the prefix removed) that should be output for the requested depth
+func forEachPackage(pkgs []string, prefix *prefixer, depth int, fn func(pkg string, pkgFormatted string)) {
+	for _, pkg := range pkgs {
+		pkgFormatted := prefix.trim(pkg)
+		if !withinDepth(pkgFormatted, depth) {
+			continue
+		}
+
+		fn(pkg, pkgFormatted)
+	}
+}
+
+// returns true when the supplied package (with the prefix removed) should be output for the requested depth (0 = all)
+func withinDepth(pkgFormatted string, depth int) bool {
+	if depth <= 0 {
+		return true
+	}
+
+	return strings.Count(pkgFormatted, "/") <= depth
+}
+
 func addLine(writer io.Writer) {
 	for x := 0; x < 138; x++ {
 		_, _ = fmt.Fprint(writer, "-")
@@ -99,19 +189,24 @@ func addLine(writer io.Writer) {
 	_, _ = fmt.Fprint(writer, "\n")
 }
 
-func addLinePrint(writer io.Writer, pkgFormatted string, cover *coverage, minCoverage float64) bool {
-	sumPercentCovered, sumStmtsCovered, sumStmts := getSummaryValues(cover)
-	selfPercentCovered, selfStmtsCovered, selfStmts := getSelfValues(cover)
+func addLinePrint(writer io.Writer, pkgFormatted string, pkg *Package, minCoverage float64) bool {
+	branch := pkg.Branch()
+
+	if pkg.TimedOut {
+		_, _ = fmt.Fprintf(writer, errHighlightStart+header1Template+errHighlightEnd, "timed out", "timed out", pkgFormatted+timedOutSuffix)
+		return false
+	}
 
 	template := lineTemplate
 	result := true
 
-	if sumPercentCovered < minCoverage {
+	if branch.Percent() < minCoverage {
 		template = errHighlightStart + lineTemplate + errHighlightEnd
 		result = false
 	}
 
-	_, _ = fmt.Fprintf(writer, template, sumPercentCovered, sumStmtsCovered, sumStmts, selfPercentCovered, selfStmtsCovered, selfStmts, pkgFormatted)
+	_, _ = fmt.Fprintf(writer, template, branch.Percent(), float64(branch.Covered), float64(branch.Statements),
+		pkg.Self.Percent(), float64(pkg.Self.Covered), float64(pkg.Self.Statements), pkgFormatted)
 
 	return result
 }
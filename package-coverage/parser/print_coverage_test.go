@@ -18,6 +18,7 @@ import (
 	"bytes"
 	"testing"
 
+	"github.com/corsc/go-tools/package-coverage/utils"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -32,7 +33,7 @@ func TestCalculateCoverage(t *testing.T) {
 		},
 	}
 
-	result := getCoverageByPackage(sampleCoverageFileContents)
+	result := parseTestProfile(t, sampleCoverageFileContents).getCoverage()
 	converted := map[string]*coverage(result)
 	assert.Equal(t, expected, converted)
 
@@ -41,7 +42,7 @@ func TestCalculateCoverage(t *testing.T) {
 	buffer := &bytes.Buffer{}
 	_, _  = buffer.Write([]byte("\n"))
 
-	printCoverage(buffer, pkgs, converted, 60, "", 0)
+	printCoverage(buffer, newReport(pkgs, converted, nil).Packages, 60, newPrefixer("", nil), 0)
 	expectedOutput := `
 ------------------------------------------------------------------------------------------------------------------------------------------
 | Branch                   | Dir                      |                                                                                  |
@@ -110,3 +111,80 @@ github.com/corsc/go-tools/package-coverage/main.go:60.70,63.27 2 0
 github.com/corsc/go-tools/package-coverage/main.go:73.2,73.15 1 0
 github.com/corsc/go-tools/package-coverage/main.go:63.27,72.3 5 0
 `
+
+func TestPrintCoverageByModule(t *testing.T) {
+	_, coverageData := getTestCoverage(t, `mode: set
+github.com/corsc/go-tools/a.go:3.24,4.12 1 1
+github.com/corsc/go-tools/package-coverage/a.go:3.24,4.12 1 1
+github.com/corsc/go-tools/package-coverage/parser/b.go:1.1,2.2 3 0
+github.com/corsc/other/c.go:1.1,2.2 1 1
+`)
+	pkgs := getSortedPackages(coverageData)
+
+	modules := []*utils.Module{
+		{Dir: "/src/go-tools/", Path: "github.com/corsc/go-tools"},
+		{Dir: "/src/go-tools/package-coverage/", Path: "github.com/corsc/go-tools/package-coverage"},
+	}
+
+	buffer := &bytes.Buffer{}
+	result := printCoverageByModule(buffer, modules, newReport(pkgs, coverageData, nil).Packages, 50, newPrefixer("", modules), 0)
+	assert.False(t, result)
+
+	output := buffer.String()
+	assert.Contains(t, output, "Module github.com/corsc/go-tools\n")
+	assert.Contains(t, output, "| 100.00 |      1 |      1 | 100.00 |      1 |      1 | Total ")
+	assert.Contains(t, output, "Module github.com/corsc/go-tools/package-coverage\n")
+	assert.Contains(t, output, "|  25.00 |      1 |      4 |  25.00 |      1 |      4 | Total ")
+	assert.Contains(t, output, "Other\n")
+	assert.Contains(t, output, "| github.com/corsc/other/ ")
+}
+
+func TestPrefixer_Modules(t *testing.T) {
+	pkgs, coverageData := getTestCoverage(t, `mode: set
+github.com/corsc/go-tools/a.go:3.24,4.12 1 1
+github.com/corsc/go-tools/package-coverage/parser/b.go:1.1,2.2 3 0
+github.com/corsc/other/c.go:1.1,2.2 1 1
+`)
+
+	modules := []*utils.Module{
+		{Dir: "/src/go-tools/", Path: "github.com/corsc/go-tools"},
+		{Dir: "/src/go-tools/package-coverage/", Path: "github.com/corsc/go-tools/package-coverage"},
+	}
+
+	// without a prefix, the prefix of each package is derived from its module
+	prefix := newPrefixer("", modules)
+	assert.Equal(t, "go-tools/", prefix.trim("github.com/corsc/go-tools/"))
+	assert.Equal(t, "package-coverage/parser/", prefix.trim("github.com/corsc/go-tools/package-coverage/parser/"))
+	assert.Equal(t, "github.com/corsc/other/", prefix.trim("github.com/corsc/other/"))
+
+	// the depth applies to every output
+	buffer := &bytes.Buffer{}
+	writeMarkdown(buffer, pkgs, coverageData, nil, 0, prefix, 1)
+	assert.Contains(t, buffer.String(), "`go-tools/`")
+	assert.NotContains(t, buffer.String(), "parser/")
+
+	buffer.Reset()
+	writeMarkdown(buffer, pkgs, coverageData, nil, 0, prefix, 2)
+	assert.Contains(t, buffer.String(), "`package-coverage/parser/`")
+
+	// a supplied prefix is used for every module
+	assert.Equal(t, "go-tools/package-coverage/parser/", newPrefixer("github.com/corsc/", modules).trim("github.com/corsc/go-tools/package-coverage/parser/"))
+}
+
+func TestCheckCoverage(t *testing.T) {
+	pkgs, coverageData := getTestCoverage(t, `mode: set
+github.com/corsc/fu/a.go:3.24,4.12 1 1
+github.com/corsc/fu/bar/b.go:1.1,2.2 1 1
+github.com/corsc/fu/bar/baz/c.go:1.1,2.2 4 0
+`)
+	report := newReport(pkgs, coverageData, nil)
+
+	// the same packages as printed
+	assert.Equal(t, printCoverage(&bytes.Buffer{}, report.Packages, 50, newPrefixer("github.com/corsc/", nil), 0),
+		CheckCoverage(report, 50, "github.com/corsc/", 0))
+
+	// fu/ is 33% (including its children) and fu/bar/ is 20%
+	assert.False(t, CheckCoverage(report, 30, "github.com/corsc/", 0))
+	assert.True(t, CheckCoverage(report, 20, "github.com/corsc/", 2))
+	assert.True(t, CheckCoverage(report, 30, "github.com/corsc/", 1))
+}
@@ -0,0 +1,269 @@
+// Copyright 2017 Corey Scott http://www.sage42.org/
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"context"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/corsc/go-tools/package-coverage/utils"
+)
+
+// Report is the coverage of a tree of packages.  It is returned by Load (for use as a library) and is the input of the
+// console and webhook outputs.
+type Report struct {
+	// Packages contains every package (sorted by path)
+	Packages []*Package
+
+	// Roots are the packages that are not the child of another package in the report
+	Roots []*Package
+
+	// Modules are the modules found under the base path (missing in single directory mode)
+	Modules []*utils.Module
+
+	// the loaded coverage and test results used by the outputs (see Load)
+	options      LoadOptions
+	pkgs         []string
+	coverageData coverageByPackage
+	merged       *profile
+	blocks       []block
+	results      []*testResults
+	resolver     *sourceResolver
+}
+
+// LoadOptions controls which coverage (and test results) files are loaded
+type LoadOptions struct {
+	// BasePath is the directory the coverage was calculated for
+	BasePath string
+
+	// Exclusions are the paths (and files) to exclude from the report (optional)
+	Exclusions *regexp.Regexp
+
+	// SingleDir only loads the coverage of BasePath (rather than of every directory below it)
+	SingleDir bool
+
+	// Profiles are additional coverage profiles (filepath.Glob patterns, e.g. from integration tests) to merge into the
+	// coverage (optional)
+	Profiles []string
+
+	// Attribution records which directories' tests covered each block (required by PrintAttribution)
+	Attribution bool
+}
+
+// Package is the coverage of a single package and its children
+type Package struct {
+	// Path is the import path of the package with a trailing slash (e.g. "github.com/corsc/go-tools/package-coverage/")
+	Path string
+
+	// Self is the coverage of the package itself
+	Self Counts
+
+	// Child is the coverage of all the packages below this package (not only the direct Children)
+	Child Counts
+
+	// TimedOut is set when the tests of the package did not finish within the timeout (the coverage is incomplete)
+	TimedOut bool
+
+	// Tests are the results of the tests of the package (missing when there are no results, e.g. for merged profiles)
+	Tests *Tests
+
+	// Children are the closest packages below this package
+	Children []*Package
+}
+
+// Branch returns the coverage of the package including all its children
+func (p *Package) Branch() Counts {
+	return Counts{
+		Statements: p.Self.Statements + p.Child.Statements,
+		Covered:    p.Self.Covered + p.Child.Covered,
+	}
+}
+
+// Counts is a number of statements and how many of them are covered
+type Counts struct {
+	Statements int
+	Covered    int
+}
+
+// Percent returns the percentage of statements covered (100 when there are no statements)
+func (c Counts) Percent() float64 {
+	return getPercentage(float64(c.Statements), float64(c.Covered))
+}
+
+// Tests is the results of the tests of a package
+type Tests struct {
+	Passed  int
+	Failed  int
+	Skipped int
+
+	// Failures are the names of the failed tests
+	Failures []string
+
+	// PackageFailed is set when the package failed without a failing test (e.g. build failure or panic in TestMain)
+	PackageFailed bool
+
+	// Duration is the wall-clock duration of go test (or of the tests when it is unknown)
+	Duration time.Duration
+}
+
+// Load will find and load all the coverage (and test results) files of the supplied options (see GeneratorDo.Run).
+// The files are only read once; the returned report contains everything required by the outputs.
+func Load(ctx context.Context, options LoadOptions) (*Report, error) {
+	merged, results, err := readCoverage(ctx, options)
+	if err != nil {
+		return nil, err
+	}
+
+	pkgs, coverageData := getCoverageByProfile(merged)
+	pkgs, coverageData = addTimedOut(pkgs, coverageData, results)
+
+	report := newReport(pkgs, coverageData, results)
+	report.options = options
+	report.merged = merged
+	report.blocks = merged.getBlocks()
+	report.resolver = newSourceResolver(options.BasePath)
+
+	if !options.SingleDir {
+		report.Modules, err = utils.FindAllModules(options.BasePath)
+		if err != nil {
+			utils.LogWhenVerbose("[load] unable to find modules. err: %s", err)
+		}
+	}
+
+	return report, nil
+}
+
+// Total returns the coverage of all the packages in the report (without double counting children)
+func (r *Report) Total() Counts {
+	total := Counts{}
+
+	for _, pkg := range r.Packages {
+		total.Statements += pkg.Self.Statements
+		total.Covered += pkg.Self.Covered
+	}
+
+	return total
+}
+
+// Find returns the package with the supplied import path (with or without the trailing slash) or nil when the package
+// is not in the report
+func (r *Report) Find(path string) *Package {
+	path = strings.TrimSuffix(path, "/") + "/"
+
+	for _, pkg := range r.Packages {
+		if pkg.Path == path {
+			return pkg
+		}
+	}
+
+	return nil
+}
+
+// build the report (and the package hierarchy) from the supplied coverage and test results
+func newReport(pkgs []string, coverageData coverageByPackage, results []*testResults) *Report {
+	report := &Report{
+		Packages:     make([]*Package, 0, len(pkgs)),
+		pkgs:         pkgs,
+		coverageData: coverageData,
+		results:      results,
+	}
+
+	byPath := make(map[string]*Package, len(pkgs))
+
+	// the packages are sorted so that parents are always found before their children
+	for _, pkg := range pkgs {
+		cover := coverageData[pkg]
+
+		node := &Package{
+			Path:     pkg,
+			Self:     Counts{Statements: cover.selfStatements, Covered: cover.selfCovered},
+			Child:    Counts{Statements: cover.childStatements, Covered: cover.childCovered},
+			TimedOut: cover.timedOut,
+		}
+
+		byPath[pkg] = node
+		report.Packages = append(report.Packages, node)
+
+		parent := findParentPackage(pkg, func(parent string) bool {
+			_, found := byPath[parent]
+			return found
+		})
+
+		if parent == "" {
+			report.Roots = append(report.Roots, node)
+		} else {
+			byPath[parent].Children = append(byPath[parent].Children, node)
+		}
+	}
+
+	for _, pkgResults := range results {
+		node, found := byPath[pkgResults.pkg]
+		if !found {
+			continue
+		}
+
+		node.Tests = &Tests{
+			Passed:        pkgResults.passed,
+			Failed:        pkgResults.failed,
+			Skipped:       pkgResults.skipped,
+			Failures:      pkgResults.failures,
+			PackageFailed: pkgResults.packageFailed,
+			Duration:      time.Duration(pkgResults.duration() * float64(time.Second)),
+		}
+	}
+
+	return report
+}
+
+// returns the merged blocks of the report without the blocks of the files that match the exclusions
+func (r *Report) getIncludedBlocks() []block {
+	return excludeBlocks(r.blocks, r.options.Exclusions)
+}
+
+// returns the directory the paths in the outputs are relative to
+func (r *Report) getBaseDir() string {
+	if r.options.SingleDir {
+		return singleDir(r.options.BasePath)
+	}
+
+	baseDir, err := filepath.Abs(r.options.BasePath)
+	if err != nil {
+		return r.options.BasePath
+	}
+
+	return baseDir
+}
+
+// returns the closest (longest) package the supplied package is a child of (or "" when there is none)
+func findParentPackage(pkg string, exists func(parent string) bool) string {
+	parent := strings.TrimSuffix(pkg, "/")
+
+	for {
+		index := strings.LastIndex(parent, "/")
+		if index < 0 {
+			return ""
+		}
+
+		parent = parent[:index+1]
+		if exists(parent) {
+			return parent
+		}
+
+		parent = parent[:index]
+	}
+}
@@ -0,0 +1,161 @@
+// Copyright 2017 Corey Scott http://www.sage42.org/
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/corsc/go-tools/package-coverage/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewReport(t *testing.T) {
+	pkgs, coverageData := getTestCoverage(t, `mode: set
+github.com/corsc/fu/a.go:3.24,4.12 1 1
+github.com/corsc/fu/a.go:4.12,6.3 1 0
+github.com/corsc/fu/bar/b.go:1.1,2.2 2 1
+github.com/corsc/fu/bar/baz/c.go:1.1,2.2 4 0
+github.com/corsc/fu/qux/d.go:1.1,2.2 2 2
+github.com/corsc/other/e.go:1.1,2.2 1 1
+`)
+	results := []*testResults{
+		{pkg: "github.com/corsc/fu/bar/", passed: 2, failed: 1, failures: []string{"TestB"}, wallClock: 1.5},
+		{pkg: "github.com/corsc/missing/", packageFailed: true},
+	}
+
+	report := newReport(pkgs, coverageData, results)
+	assert.Len(t, report.Packages, 5)
+
+	// each package is the child of the closest package in the report
+	assert.Len(t, report.Roots, 2)
+	assert.Equal(t, "github.com/corsc/fu/", report.Roots[0].Path)
+	assert.Equal(t, "github.com/corsc/other/", report.Roots[1].Path)
+
+	fu := report.Roots[0]
+	assert.Len(t, fu.Children, 2)
+	assert.Equal(t, "github.com/corsc/fu/bar/", fu.Children[0].Path)
+	assert.Equal(t, "github.com/corsc/fu/qux/", fu.Children[1].Path)
+	assert.Equal(t, "github.com/corsc/fu/bar/baz/", fu.Children[0].Children[0].Path)
+
+	assert.Equal(t, Counts{Statements: 2, Covered: 1}, fu.Self)
+	assert.Equal(t, Counts{Statements: 8, Covered: 4}, fu.Child)
+	assert.Equal(t, Counts{Statements: 10, Covered: 5}, fu.Branch())
+	assert.Equal(t, 50.0, fu.Branch().Percent())
+	assert.Nil(t, fu.Tests)
+
+	bar := report.Find("github.com/corsc/fu/bar")
+	assert.Equal(t, &Tests{Passed: 2, Failed: 1, Failures: []string{"TestB"}, Duration: 1500 * time.Millisecond}, bar.Tests)
+
+	assert.Nil(t, report.Find("github.com/corsc/missing/"))
+	assert.Equal(t, Counts{Statements: 11, Covered: 6}, report.Total())
+}
+
+func TestCounts_Percent(t *testing.T) {
+	assert.Equal(t, 100.0, Counts{}.Percent())
+	assert.Equal(t, 25.0, Counts{Statements: 4, Covered: 1}.Percent())
+}
+
+func TestLoad_OutputsDoNotReadTheProfilesAgain(t *testing.T) {
+	dir, err := ioutil.TempDir("", "report")
+	assert.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+
+	filename := writeTestOutput(t, dir, "", "profile.cov", "mode: set\ngithub.com/corsc/fu/a.go:3.24,4.12 1 1\ngithub.com/corsc/fu/a.go:4.12,6.3 1 0\n")
+
+	report, err := Load(context.Background(), LoadOptions{BasePath: dir})
+	assert.NoError(t, err)
+
+	// the outputs only use the loaded report
+	assert.NoError(t, os.Remove(filename))
+
+	buffer := &bytes.Buffer{}
+	assert.NoError(t, JSONCoverage(buffer, report, 0, "github.com/corsc/"))
+	assert.Contains(t, buffer.String(), `"package": "fu/"`)
+
+	buffer.Reset()
+	assert.NoError(t, CoberturaCoverage(buffer, report, "github.com/corsc/"))
+	assert.Contains(t, buffer.String(), `filename="fu/a.go"`)
+}
+
+func TestLoad_Error(t *testing.T) {
+	dir, err := ioutil.TempDir("", "report")
+	assert.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+
+	// there is no coverage file
+	outputDir, err := utils.GetOutputDir(dir)
+	assert.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(filepath.Dir(filepath.Clean(outputDir)))
+	}()
+
+	report, err := Load(context.Background(), LoadOptions{BasePath: dir, SingleDir: true})
+	assert.Error(t, err)
+	assert.Nil(t, report)
+}
+
+func TestLoad_OutputDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "report")
+	assert.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+
+	// files in the source tree are not part of the report
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "profile.cov"), []byte("mode: set\ngithub.com/corsc/other/a.go:1.1,2.2 1 1\n"), 0600))
+
+	writeTestOutput(t, dir, "fu", "profile.cov", "mode: set\ngithub.com/corsc/fu/a.go:3.24,4.12 1 1\n")
+	writeTestOutput(t, dir, "bar", "profile.cov", "mode: set\ngithub.com/corsc/bar/b.go:3.24,4.12 1 0\n")
+	writeTestOutput(t, dir, "bar", utils.TestResultsFilename, `{"Action":"fail","Package":"github.com/corsc/bar","Test":"TestB"}`+"\n")
+
+	report, err := Load(context.Background(), LoadOptions{BasePath: dir})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"github.com/corsc/bar/", "github.com/corsc/fu/"}, report.pkgs)
+	assert.Len(t, report.results, 1)
+
+	// the exclusions apply to the directories the output was generated for
+	report, err = Load(context.Background(), LoadOptions{BasePath: dir, Exclusions: regexp.MustCompile(`/bar/`)})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"github.com/corsc/fu/"}, report.pkgs)
+	assert.Empty(t, report.results)
+}
+
+// write a file into the output directory of the base path (as the generator would) that is removed after the test
+func writeTestOutput(t *testing.T, basePath string, relPath string, filename string, contents string) string {
+	outputDir, err := utils.GetOutputDir(basePath)
+	assert.NoError(t, err)
+	t.Cleanup(func() {
+		_ = os.RemoveAll(filepath.Dir(filepath.Clean(outputDir)))
+	})
+
+	dir := filepath.Join(outputDir, relPath)
+	assert.NoError(t, os.MkdirAll(dir, 0700))
+
+	output := filepath.Join(dir, filename)
+	assert.NoError(t, ioutil.WriteFile(output, []byte(contents), 0600))
+
+	return output
+}
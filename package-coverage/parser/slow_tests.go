@@ -0,0 +1,76 @@
+// Copyright 2017 Corey Scott http://www.sage42.org/
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+const (
+	slowestHeaderTemplate = "| %10s | %-7s | %-113s |\n"
+	slowestLineTemplate   = "| %9.2fs | %-7s | %-113s |\n"
+)
+
+// PrintSlowest will print the packages in the report that took the longest to test (the wall-clock duration of go
+// test, including building the tests) along with whether they passed, failed or timed out
+func PrintSlowest(writer io.Writer, report *Report, limit int, prefix string) {
+	printSlowest(writer, report.results, limit, newPrefixer(prefix, report.Modules))
+}
+
+func printSlowest(writer io.Writer, results []*testResults, limit int, prefix *prefixer) {
+	if len(results) == 0 || limit <= 0 {
+		return
+	}
+
+	sorted := append([]*testResults{}, results...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].duration() > sorted[j].duration()
+	})
+
+	if len(sorted) > limit {
+		sorted = sorted[:limit]
+	}
+
+	_, _ = fmt.Fprint(writer, "Slowest packages\n")
+
+	addLine(writer)
+	_, _ = fmt.Fprintf(writer, slowestHeaderTemplate, "Duration", "Status", "Package")
+	addLine(writer)
+
+	for _, pkgResults := range sorted {
+		template := slowestLineTemplate
+		if !pkgResults.ok() {
+			template = errHighlightStart + slowestLineTemplate + errHighlightEnd
+		}
+
+		_, _ = fmt.Fprintf(writer, template, pkgResults.duration(), getTestStatus(pkgResults), prefix.trim(pkgResults.pkg))
+	}
+	addLine(writer)
+}
+
+func getTestStatus(results *testResults) string {
+	switch {
+	case results.timedOut:
+		return "TIMEOUT"
+
+	case !results.ok():
+		return "FAIL"
+
+	default:
+		return "ok"
+	}
+}
@@ -0,0 +1,47 @@
+// Copyright 2017 Corey Scott http://www.sage42.org/
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrintSlowest(t *testing.T) {
+	results := []*testResults{
+		{pkg: "github.com/corsc/fu/", passed: 1, elapsed: 0.5, wallClock: 1.5},
+		{pkg: "github.com/corsc/fu/bar/", failed: 1, elapsed: 0.1},
+		{pkg: "github.com/corsc/fu/baz/", timedOut: true, wallClock: 60},
+	}
+
+	writer := &bytes.Buffer{}
+	printSlowest(writer, results, 2, newPrefixer("github.com/corsc/", nil))
+
+	output := writer.String()
+	assert.Contains(t, output, "Slowest packages\n")
+	assert.Contains(t, output, "|     60.00s | TIMEOUT | fu/baz/")
+	assert.Contains(t, output, "|      1.50s | ok      | fu/ ")
+	assert.NotContains(t, output, "fu/bar/")
+	assert.True(t, bytes.Index(writer.Bytes(), []byte("fu/baz/")) < bytes.Index(writer.Bytes(), []byte("fu/ ")))
+}
+
+func TestPrintSlowest_Disabled(t *testing.T) {
+	writer := &bytes.Buffer{}
+	printSlowest(writer, []*testResults{{pkg: "github.com/corsc/fu/"}}, 0, newPrefixer("", nil))
+
+	assert.Empty(t, writer.String())
+}
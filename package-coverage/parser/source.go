@@ -0,0 +1,76 @@
+// Copyright 2017 Corey Scott http://www.sage42.org/
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"go/build"
+	"path/filepath"
+	"strings"
+
+	"github.com/corsc/go-tools/package-coverage/utils"
+)
+
+// sourceResolver converts the package qualified filenames found in coverage profiles into locations on disk
+type sourceResolver struct {
+	// srcDir is the directory the packages are resolved from (this determines the module/GOPATH used)
+	srcDir string
+
+	// dirs caches the directory of each package
+	dirs map[string]string
+}
+
+func newSourceResolver(srcDir string) *sourceResolver {
+	absDir, err := filepath.Abs(srcDir)
+	if err != nil {
+		absDir = srcDir
+	}
+
+	return &sourceResolver{
+		srcDir: absDir,
+		dirs:   map[string]string{},
+	}
+}
+
+// resolve returns the location on disk of the supplied file (or the package qualified filename when it cannot be found)
+func (r *sourceResolver) resolve(pkg string, file string) string {
+	dir, found := r.dirs[pkg]
+	if !found {
+		dir = r.findDir(pkg)
+		r.dirs[pkg] = dir
+	}
+
+	if dir == "" {
+		return pkg + file
+	}
+
+	return filepath.Join(dir, file)
+}
+
+func (r *sourceResolver) findDir(pkg string) string {
+	importPath := strings.TrimSuffix(pkg, "/")
+
+	// packages outside of GOPATH and modules are recorded with their absolute path prefixed with "_"
+	if strings.HasPrefix(importPath, "_/") {
+		return strings.TrimPrefix(importPath, "_")
+	}
+
+	buildPkg, err := build.Import(importPath, r.srcDir, build.FindOnly)
+	if err != nil {
+		utils.LogWhenVerbose("[source] unable to find the directory of package '%s'. err: %s", importPath, err)
+		return ""
+	}
+
+	return buildPkg.Dir
+}
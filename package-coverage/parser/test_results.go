@@ -0,0 +1,335 @@
+// Copyright 2017 Corey Scott http://www.sage42.org/
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/corsc/go-tools/package-coverage/utils"
+)
+
+const (
+	testsHeaderTemplate = "| %6s | %6s | %6s | %6s | %-98s |\n"
+	testsLineTemplate   = "| %6d | %6d | %6d | %6s | %-98s |\n"
+
+	// the fake test the generator adds to directories without tests (it is not included in the results)
+	fakeTestName = "TestThisTestDoesntReallyTestAnything"
+
+	// events can include long lines of test output
+	maxTestEventSize = 10 * 1024 * 1024
+
+	// actions added to the test results by the generator (see generator.addRunEvents)
+	actionRun     = "run"
+	actionTimeout = "timeout"
+
+	// output of go test when it stopped the tests after the -timeout
+	timeoutPanic = "panic: test timed out after"
+
+	// added to the package name of timed out packages in the console output
+	timedOutSuffix = " (timed out)"
+)
+
+// testEvent is a single event from the output of go test -json (see "go doc test2json")
+type testEvent struct {
+	Action  string
+	Package string
+	Test    string
+	Elapsed float64
+	Output  string
+}
+
+// testResults contains the results of the tests of a single package
+type testResults struct {
+	pkg      string
+	passed   int
+	skipped  int
+	failed   int
+	failures []string
+
+	// packageFailed is set when the package failed without a failing test (e.g. build failure or panic in TestMain)
+	packageFailed bool
+
+	// elapsed is the duration (in seconds) of the package's tests
+	elapsed float64
+
+	// wallClock is the duration (in seconds) of go test including building the tests (0 means unknown)
+	wallClock float64
+
+	// timedOut is set when the tests did not finish within the timeout
+	timedOut bool
+
+	// output is the output that is not from a particular test (e.g. build errors and the final PASS/FAIL)
+	output string
+
+	tests []*testCase
+}
+
+// testCase is the result of a single test (or sub-test)
+type testCase struct {
+	name    string
+	action  string
+	elapsed float64
+	output  string
+}
+
+// returns the test case of the supplied name (creating it when required)
+func (r *testResults) getTestCase(name string) *testCase {
+	// search backwards as events usually relate to the most recently started tests
+	for index := len(r.tests) - 1; index >= 0; index-- {
+		if r.tests[index].name == name {
+			return r.tests[index]
+		}
+	}
+
+	test := &testCase{name: name}
+	r.tests = append(r.tests, test)
+	return test
+}
+
+func (r *testResults) ok() bool {
+	return r.failed == 0 && !r.packageFailed && !r.timedOut
+}
+
+// returns the wall-clock duration of go test (or the duration of the tests when it is unknown)
+func (r *testResults) duration() float64 {
+	if r.wallClock > 0 {
+		return r.wallClock
+	}
+	return r.elapsed
+}
+
+// PrintTestResults will print the pass/fail/skip counts of the tests of each package in the report (alongside the
+// coverage of the package) and the names of any failed tests.  Returns false when any tests failed.
+func PrintTestResults(writer io.Writer, report *Report, prefix string) bool {
+	if len(report.results) == 0 {
+		return true
+	}
+
+	return printTestResults(writer, report.results, report.Packages, newPrefixer(prefix, report.Modules))
+}
+
+// find and read all the test results files in the output directory of the supplied base path
+func readTestResults(ctx context.Context, outputDir string, basePath string, exclusionsMatcher *regexp.Regexp) ([]*testResults, error) {
+	paths, err := findOutputFiles(outputDir, utils.FindAllTestResultFiles)
+	if err != nil {
+		return nil, fmt.Errorf("error finding test results files: %w", err)
+	}
+
+	return getTestResults(ctx, outputDir, basePath, paths, exclusionsMatcher)
+}
+
+// read the test results file from a single directory (if there is one)
+func readTestResultsSingle(ctx context.Context, outputDir string, path string) ([]*testResults, error) {
+	filename := outputDir + utils.TestResultsFilename
+	if _, err := os.Stat(filename); err != nil {
+		return nil, nil
+	}
+
+	return getTestResults(ctx, outputDir, path, []string{filename}, nil)
+}
+
+// load and combine the results from all the supplied test results files (in the output directory of the base path)
+// that are not excluded (sorted by package)
+func getTestResults(ctx context.Context, outputDir string, basePath string, paths []string, exclusionsMatcher *regexp.Regexp) ([]*testResults, error) {
+	resultsByPkg := map[string]*testResults{}
+
+	for _, path := range paths {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		source := utils.GetSourcePath(outputDir, basePath, filepath.Dir(path))
+		if exclusionsMatcher != nil && exclusionsMatcher.FindString(source) != "" {
+			utils.LogWhenVerbose("[tests] test results for path '%s' skipped due to exclusions regex '%s'",
+				source, exclusionsMatcher.String())
+			continue
+		}
+
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+
+		parseTestEvents(file, resultsByPkg)
+		_ = file.Close()
+	}
+
+	output := make([]*testResults, 0, len(resultsByPkg))
+	for _, results := range resultsByPkg {
+		output = append(output, results)
+	}
+
+	sort.Slice(output, func(i, j int) bool {
+		return output[i].pkg < output[j].pkg
+	})
+
+	return output, nil
+}
+
+// add the results from the supplied go test -json event stream (keyed by package in the same format as the coverage)
+func parseTestEvents(reader io.Reader, resultsByPkg map[string]*testResults) {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(nil, maxTestEventSize)
+
+	for scanner.Scan() {
+		event := testEvent{}
+		err := json.Unmarshal(scanner.Bytes(), &event)
+		if err != nil || event.Package == "" {
+			utils.LogWhenVerbose("[tests] skipped line '%s'", scanner.Text())
+			continue
+		}
+
+		if event.Test == fakeTestName {
+			continue
+		}
+
+		pkg := event.Package + "/"
+		results, found := resultsByPkg[pkg]
+		if !found {
+			results = &testResults{pkg: pkg}
+			resultsByPkg[pkg] = results
+		}
+
+		addTestEvent(results, event)
+	}
+
+	if err := scanner.Err(); err != nil {
+		utils.LogAlways("[tests] error while reading test results. err: %s", err)
+	}
+}
+
+func addTestEvent(results *testResults, event testEvent) {
+	if event.Test == "" {
+		addPackageEvent(results, event)
+		return
+	}
+
+	test := results.getTestCase(event.Test)
+
+	switch event.Action {
+	case "output":
+		test.output += event.Output
+		if strings.HasPrefix(event.Output, timeoutPanic) {
+			results.timedOut = true
+		}
+
+	case "pass":
+		results.passed++
+		test.action = event.Action
+		test.elapsed = event.Elapsed
+
+	case "skip":
+		results.skipped++
+		test.action = event.Action
+		test.elapsed = event.Elapsed
+
+	case "fail":
+		results.failed++
+		results.failures = append(results.failures, event.Test)
+		test.action = event.Action
+		test.elapsed = event.Elapsed
+	}
+}
+
+func addPackageEvent(results *testResults, event testEvent) {
+	switch event.Action {
+	case "output", "build-output":
+		results.output += event.Output
+		if strings.HasPrefix(event.Output, timeoutPanic) {
+			results.timedOut = true
+		}
+
+	case actionRun:
+		results.wallClock = event.Elapsed
+
+	case actionTimeout:
+		results.output += event.Output
+		results.wallClock = event.Elapsed
+		results.timedOut = true
+
+	case "pass", "skip":
+		results.elapsed = event.Elapsed
+
+	case "fail":
+		results.elapsed = event.Elapsed
+		if results.failed == 0 {
+			results.packageFailed = true
+		}
+	}
+}
+
+func printTestResults(writer io.Writer, results []*testResults, pkgs []*Package, prefix *prefixer) bool {
+	if len(results) == 0 {
+		return true
+	}
+
+	pkgsByPath := make(map[string]*Package, len(pkgs))
+	for _, pkg := range pkgs {
+		pkgsByPath[pkg.Path] = pkg
+	}
+
+	_, _ = fmt.Fprint(writer, "Test results\n")
+
+	addLine(writer)
+	_, _ = fmt.Fprintf(writer, testsHeaderTemplate, "Pass", "Fail", "Skip", "Cov%", "Package")
+	addLine(writer)
+
+	testsOk := true
+	for _, pkgResults := range results {
+		cover := "-"
+		if pkg, found := pkgsByPath[pkgResults.pkg]; found {
+			cover = fmt.Sprintf("%.2f", pkg.Branch().Percent())
+		}
+
+		template := testsLineTemplate
+		if !pkgResults.ok() {
+			template = errHighlightStart + testsLineTemplate + errHighlightEnd
+			testsOk = false
+		}
+
+		pkgFormatted := prefix.trim(pkgResults.pkg)
+		if pkgResults.timedOut {
+			pkgFormatted += timedOutSuffix
+		}
+
+		_, _ = fmt.Fprintf(writer, template, pkgResults.passed, pkgResults.failed, pkgResults.skipped, cover, pkgFormatted)
+	}
+	addLine(writer)
+
+	for _, pkgResults := range results {
+		pkgFormatted := prefix.trim(pkgResults.pkg)
+
+		if pkgResults.timedOut {
+			_, _ = fmt.Fprintf(writer, "TIMEOUT %s (%.2fs; see the go test output)\n", pkgFormatted, pkgResults.duration())
+		} else if pkgResults.packageFailed {
+			_, _ = fmt.Fprintf(writer, "FAIL %s (package failed; see the go test output)\n", pkgFormatted)
+		}
+
+		for _, name := range pkgResults.failures {
+			_, _ = fmt.Fprintf(writer, "FAIL %s %s\n", pkgFormatted, name)
+		}
+	}
+
+	return testsOk
+}
@@ -0,0 +1,117 @@
+// Copyright 2017 Corey Scott http://www.sage42.org/
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTestEvents(t *testing.T) {
+	in := `{"Action":"start","Package":"github.com/corsc/fu"}
+{"Action":"run","Package":"github.com/corsc/fu","Test":"TestA"}
+{"Action":"output","Package":"github.com/corsc/fu","Test":"TestA","Output":"--- FAIL: TestA (0.00s)\n"}
+{"Action":"fail","Package":"github.com/corsc/fu","Test":"TestA","Elapsed":0}
+{"Action":"pass","Package":"github.com/corsc/fu","Test":"TestB","Elapsed":0}
+{"Action":"skip","Package":"github.com/corsc/fu","Test":"TestC","Elapsed":0}
+{"Action":"fail","Package":"github.com/corsc/fu","Elapsed":0.1}
+not an event
+{"Action":"pass","Package":"github.com/corsc/fu/bar","Test":"TestThisTestDoesntReallyTestAnything","Elapsed":0}
+{"Action":"pass","Package":"github.com/corsc/fu/bar","Elapsed":0.1}
+{"Action":"build-output","Package":"github.com/corsc/fu/baz","Output":"# github.com/corsc/fu/baz\n"}
+{"Action":"fail","Package":"github.com/corsc/fu/baz","Elapsed":0}
+`
+	result := map[string]*testResults{}
+	parseTestEvents(strings.NewReader(in), result)
+
+	expected := map[string]*testResults{
+		"github.com/corsc/fu/": {
+			pkg:      "github.com/corsc/fu/",
+			passed:   1,
+			skipped:  1,
+			failed:   1,
+			failures: []string{"TestA"},
+			elapsed:  0.1,
+			tests: []*testCase{
+				{name: "TestA", action: "fail", output: "--- FAIL: TestA (0.00s)\n"},
+				{name: "TestB", action: "pass"},
+				{name: "TestC", action: "skip"},
+			},
+		},
+		"github.com/corsc/fu/bar/": {
+			pkg:     "github.com/corsc/fu/bar/",
+			elapsed: 0.1,
+		},
+		"github.com/corsc/fu/baz/": {
+			pkg:           "github.com/corsc/fu/baz/",
+			packageFailed: true,
+			output:        "# github.com/corsc/fu/baz\n",
+		},
+	}
+	assert.Equal(t, expected, result)
+}
+
+func TestPrintTestResults(t *testing.T) {
+	pkgs, coverageData := getTestCoverage(t, `mode: set
+github.com/corsc/fu/a.go:3.24,4.12 1 1
+github.com/corsc/fu/a.go:4.12,6.3 1 0
+`)
+	packages := newReport(pkgs, coverageData, nil).Packages
+
+	results := []*testResults{
+		{pkg: "github.com/corsc/fu/", passed: 2, failed: 1, failures: []string{"TestA"}},
+		{pkg: "github.com/corsc/fu/bar/", passed: 1},
+	}
+
+	buffer := &bytes.Buffer{}
+	assert.False(t, printTestResults(buffer, results, packages, newPrefixer("github.com/corsc/", nil)))
+	assert.Contains(t, buffer.String(), "|      2 |      1 |      0 |  50.00 | fu/ ")
+	assert.Contains(t, buffer.String(), "|      1 |      0 |      0 |      - | fu/bar/ ")
+	assert.Contains(t, buffer.String(), "FAIL fu/ TestA\n")
+
+	buffer.Reset()
+	assert.True(t, printTestResults(buffer, results[1:], packages, newPrefixer("github.com/corsc/", nil)))
+	assert.NotContains(t, buffer.String(), "FAIL")
+
+	buffer.Reset()
+	assert.True(t, printTestResults(buffer, nil, packages, newPrefixer("github.com/corsc/", nil)))
+	assert.Empty(t, buffer.String())
+}
+
+func TestParseTestEvents_TimedOut(t *testing.T) {
+	in := `{"Action":"start","Package":"github.com/corsc/fu"}
+{"Action":"run","Package":"github.com/corsc/fu","Test":"TestSlow"}
+{"Action":"output","Package":"github.com/corsc/fu","Test":"TestSlow","Output":"panic: test timed out after 1s\n"}
+{"Action":"fail","Package":"github.com/corsc/fu","Elapsed":1.2}
+{"Action":"run","Package":"github.com/corsc/fu","Elapsed":1.5}
+{"Action":"run","Package":"github.com/corsc/fu/bar","Elapsed":2.5}
+{"Action":"timeout","Package":"github.com/corsc/fu/bar","Elapsed":2.5,"Output":"go test killed after 2.5s\n"}
+`
+	result := map[string]*testResults{}
+	parseTestEvents(strings.NewReader(in), result)
+
+	fu := result["github.com/corsc/fu/"]
+	assert.True(t, fu.timedOut)
+	assert.False(t, fu.ok())
+	assert.Equal(t, 1.5, fu.duration())
+
+	bar := result["github.com/corsc/fu/bar/"]
+	assert.True(t, bar.timedOut)
+	assert.Equal(t, 2.5, bar.duration())
+	assert.Equal(t, "go test killed after 2.5s\n", bar.output)
+}
@@ -0,0 +1,307 @@
+// Copyright 2017 Corey Scott http://www.sage42.org/
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"math"
+	"sort"
+)
+
+const (
+	treemapWidth  = 1200
+	treemapHeight = 800
+
+	// space reserved around (and for the label at the top of) packages with children
+	treemapPadding     = 2
+	treemapLabelHeight = 14
+
+	// minimum size of a rectangle for it to be labelled
+	treemapMinLabelHeight = 14
+)
+
+const treemapHeader = `<svg xmlns="http://www.w3.org/2000/svg" width="%[1]d" height="%[2]d" viewBox="0 0 %[1]d %[2]d" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="10">
+<style>text { pointer-events: none; }</style>
+<rect width="%[1]d" height="%[2]d" fill="#fff"/>
+`
+
+const (
+	treemapRectTemplate  = "<g><title>%s</title><rect x=\"%.1f\" y=\"%.1f\" width=\"%.1f\" height=\"%.1f\" fill=\"%s\" stroke=\"#fff\"/></g>\n"
+	treemapLabelTemplate = "<text x=\"%.1f\" y=\"%.1f\" fill=\"%s\">%s</text>\n"
+	treemapFooter        = "</svg>\n"
+	treemapGroupColor    = "#e8e8e8"
+	treemapTimedOutColor = "#999"
+)
+
+// treemapNode is a package in the package hierarchy (see Package.Children) and its name without the prefix
+type treemapNode struct {
+	pkg      *Package
+	name     string
+	children []*treemapNode
+}
+
+// weight is the number of statements in the package and all its children.
+// Packages that timed out (usually without any statements) are always given some area so that they are visible.
+func (n *treemapNode) weight() float64 {
+	weight := float64(n.pkg.Branch().Statements)
+	if n.pkg.TimedOut && weight < 1 {
+		return 1
+	}
+	return weight
+}
+
+// treemapItem is either a package or (when self is true) the statements in the package itself
+type treemapItem struct {
+	node   *treemapNode
+	self   bool
+	weight float64
+}
+
+type treemapRect struct {
+	x, y, w, h float64
+}
+
+// TreemapCoverage will output the coverage in the report as an SVG treemap.
+// The area of each package is proportional to its statements and the color to its coverage.
+func TreemapCoverage(writer io.Writer, report *Report, prefix string) error {
+	return writeTreemap(writer, buildTreemap(report.Roots, newPrefixer(prefix, report.Modules)), treemapWidth, treemapHeight)
+}
+
+// build the nodes of the supplied packages (and their children)
+func buildTreemap(pkgs []*Package, prefix *prefixer) []*treemapNode {
+	nodes := make([]*treemapNode, 0, len(pkgs))
+
+	for _, pkg := range pkgs {
+		nodes = append(nodes, &treemapNode{
+			pkg:      pkg,
+			name:     prefix.trim(pkg.Path),
+			children: buildTreemap(pkg.Children, prefix),
+		})
+	}
+
+	return nodes
+}
+
+func writeTreemap(output io.Writer, roots []*treemapNode, width int, height int) error {
+	writer := newErrWriter(output)
+
+	_, _ = fmt.Fprintf(writer, treemapHeader, width, height)
+
+	items := make([]*treemapItem, 0, len(roots))
+	for _, root := range roots {
+		items = append(items, &treemapItem{node: root, weight: root.weight()})
+	}
+
+	writeTreemapItems(writer, items, treemapRect{w: float64(width), h: float64(height)})
+
+	_, _ = fmt.Fprint(writer, treemapFooter)
+
+	if writer.err != nil {
+		return fmt.Errorf("error writing treemap output: %w", writer.err)
+	}
+
+	return nil
+}
+
+func writeTreemapItems(writer io.Writer, items []*treemapItem, area treemapRect) {
+	rects := squarify(items, area)
+
+	for index, item := range items {
+		rect := rects[index]
+		if rect.w <= 0 || rect.h <= 0 {
+			continue
+		}
+
+		node := item.node
+		tooltip := html.EscapeString(getTreemapTooltip(node))
+
+		// packages without children (and the statements of the package itself) are colored by their coverage
+		if item.self || len(node.children) == 0 {
+			color := getTreemapColor(node.pkg.Self.Percent())
+			if node.pkg.TimedOut {
+				color = treemapTimedOutColor
+			}
+
+			_, _ = fmt.Fprintf(writer, treemapRectTemplate, tooltip, rect.x, rect.y, rect.w, rect.h, color)
+
+			if !item.self {
+				writeTreemapLabel(writer, node.name, rect, "#000")
+			}
+			continue
+		}
+
+		// packages with children contain a rectangle for their own statements and one for each child
+		_, _ = fmt.Fprintf(writer, treemapRectTemplate, tooltip, rect.x, rect.y, rect.w, rect.h, treemapGroupColor)
+
+		inner := rect
+		if rect.h > treemapLabelHeight+2*treemapPadding && rect.w > 2*treemapPadding {
+			writeTreemapLabel(writer, node.name, rect, "#333")
+
+			inner = treemapRect{
+				x: rect.x + treemapPadding,
+				y: rect.y + treemapLabelHeight,
+				w: rect.w - 2*treemapPadding,
+				h: rect.h - treemapLabelHeight - treemapPadding,
+			}
+		}
+
+		var children []*treemapItem
+		if node.pkg.Self.Statements > 0 {
+			children = append(children, &treemapItem{node: node, self: true, weight: float64(node.pkg.Self.Statements)})
+		}
+
+		for _, child := range node.children {
+			children = append(children, &treemapItem{node: child, weight: child.weight()})
+		}
+
+		writeTreemapItems(writer, children, inner)
+	}
+}
+
+// label the rectangle (when the label fits)
+func writeTreemapLabel(writer io.Writer, name string, rect treemapRect, color string) {
+	if rect.h < treemapMinLabelHeight || float64(getBadgeTextWidth(name)) > rect.w-2*treemapPadding {
+		return
+	}
+
+	_, _ = fmt.Fprintf(writer, treemapLabelTemplate, rect.x+treemapPadding+1, rect.y+11, color, html.EscapeString(name))
+}
+
+func getTreemapTooltip(node *treemapNode) string {
+	pkg := node.pkg
+	if pkg.TimedOut {
+		return node.name + timedOutSuffix
+	}
+
+	branch := pkg.Branch()
+
+	return fmt.Sprintf("%s\nbranch: %.2f%% (%d of %d statements)\nself: %.2f%% (%d of %d statements)\nchild: %.2f%% (%d of %d statements)",
+		node.name,
+		branch.Percent(), branch.Covered, branch.Statements,
+		pkg.Self.Percent(), pkg.Self.Covered, pkg.Self.Statements,
+		pkg.Child.Percent(), pkg.Child.Covered, pkg.Child.Statements)
+}
+
+// returns a color from red (0%) through yellow (50%) to green (100%)
+func getTreemapColor(covered float64) string {
+	return fmt.Sprintf("hsl(%.0f, 70%%, 55%%)", math.Max(0, math.Min(100, covered))*1.2)
+}
+
+// lay the items out in the area using the squarified treemap algorithm (Bruls, Huizing and van Wijk) so that the area
+// of each rectangle is proportional to the weight of the item and the rectangles are as square as possible.
+// Returns the rectangle of each item (in the order of the items).
+func squarify(items []*treemapItem, area treemapRect) []treemapRect {
+	output := make([]treemapRect, len(items))
+
+	total := 0.0
+	for _, item := range items {
+		total += item.weight
+	}
+
+	if total <= 0 || area.w <= 0 || area.h <= 0 {
+		return output
+	}
+
+	// largest first (the algorithm works best when the items are sorted)
+	order := make([]int, 0, len(items))
+	for index, item := range items {
+		if item.weight > 0 {
+			order = append(order, index)
+		}
+	}
+
+	sort.SliceStable(order, func(i, j int) bool {
+		return items[order[i]].weight > items[order[j]].weight
+	})
+
+	scale := area.w * area.h / total
+	remaining := area
+	var row []int
+
+	for len(order) > 0 {
+		next := append(append([]int{}, row...), order[0])
+		side := math.Min(remaining.w, remaining.h)
+
+		if len(row) == 0 || worstRatio(items, next, scale, side) <= worstRatio(items, row, scale, side) {
+			row = next
+			order = order[1:]
+			continue
+		}
+
+		remaining = layoutRow(items, row, scale, remaining, output)
+		row = nil
+	}
+
+	if len(row) > 0 {
+		layoutRow(items, row, scale, remaining, output)
+	}
+
+	return output
+}
+
+// returns the worst (highest) aspect ratio of the items in the row when laid out along the side
+func worstRatio(items []*treemapItem, row []int, scale float64, side float64) float64 {
+	sum := 0.0
+	minArea := math.MaxFloat64
+	maxArea := 0.0
+
+	for _, index := range row {
+		itemArea := items[index].weight * scale
+		sum += itemArea
+		minArea = math.Min(minArea, itemArea)
+		maxArea = math.Max(maxArea, itemArea)
+	}
+
+	sideSquared := side * side
+	sumSquared := sum * sum
+
+	return math.Max(sideSquared*maxArea/sumSquared, sumSquared/(sideSquared*minArea))
+}
+
+// lay the row out along the shorter side of the remaining area and return the area that is left
+func layoutRow(items []*treemapItem, row []int, scale float64, remaining treemapRect, output []treemapRect) treemapRect {
+	sum := 0.0
+	for _, index := range row {
+		sum += items[index].weight * scale
+	}
+
+	if remaining.w >= remaining.h {
+		// a column on the left
+		width := sum / remaining.h
+		y := remaining.y
+
+		for _, index := range row {
+			height := items[index].weight * scale / width
+			output[index] = treemapRect{x: remaining.x, y: y, w: width, h: height}
+			y += height
+		}
+
+		return treemapRect{x: remaining.x + width, y: remaining.y, w: remaining.w - width, h: remaining.h}
+	}
+
+	// a row at the top
+	height := sum / remaining.w
+	x := remaining.x
+
+	for _, index := range row {
+		width := items[index].weight * scale / height
+		output[index] = treemapRect{x: x, y: remaining.y, w: width, h: height}
+		x += width
+	}
+
+	return treemapRect{x: remaining.x, y: remaining.y + height, w: remaining.w, h: remaining.h - height}
+}
@@ -0,0 +1,100 @@
+// Copyright 2017 Corey Scott http://www.sage42.org/
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildTreemap(t *testing.T) {
+	pkgs, coverageData := getTestCoverage(t, `mode: set
+github.com/corsc/fu/a.go:3.24,4.12 1 1
+github.com/corsc/fu/bar/b.go:1.1,2.2 2 1
+github.com/corsc/fu/bar/baz/c.go:1.1,2.2 4 0
+github.com/corsc/fu/qux/d.go:1.1,2.2 3 1
+github.com/corsc/other/e.go:1.1,2.2 5 1
+`)
+
+	roots := buildTreemap(newReport(pkgs, coverageData, nil).Roots, newPrefixer("github.com/corsc/", nil))
+	assert.Len(t, roots, 2)
+
+	assert.Equal(t, "fu/", roots[0].name)
+	assert.Equal(t, float64(10), roots[0].weight())
+	assert.Len(t, roots[0].children, 2)
+	assert.Equal(t, "fu/bar/", roots[0].children[0].name)
+	assert.Equal(t, "fu/bar/baz/", roots[0].children[0].children[0].name)
+	assert.Equal(t, "fu/qux/", roots[0].children[1].name)
+
+	assert.Equal(t, "other/", roots[1].name)
+	assert.Empty(t, roots[1].children)
+}
+
+func TestSquarify(t *testing.T) {
+	items := []*treemapItem{
+		{weight: 2},
+		{weight: 6},
+		{weight: 0},
+		{weight: 4},
+	}
+
+	area := treemapRect{x: 10, y: 20, w: 6, h: 4}
+	result := squarify(items, area)
+	assert.Len(t, result, 4)
+
+	// area is proportional to the weight
+	for index, item := range items {
+		assert.InDelta(t, item.weight*2, result[index].w*result[index].h, 0.0001)
+	}
+
+	// largest first, starting at the top left
+	assert.Equal(t, treemapRect{x: 10, y: 20, w: 3, h: 4}, result[1])
+	assert.Equal(t, treemapRect{}, result[2])
+
+	// everything is within the area
+	for _, rect := range []treemapRect{result[0], result[1], result[3]} {
+		assert.True(t, rect.x >= 10 && rect.x+rect.w <= 16.0001)
+		assert.True(t, rect.y >= 20 && rect.y+rect.h <= 24.0001)
+	}
+}
+
+func TestWriteTreemap(t *testing.T) {
+	pkgs, coverageData := getTestCoverage(t, `mode: set
+github.com/corsc/fu/a.go:3.24,4.12 1 1
+github.com/corsc/fu/bar/b.go:1.1,2.2 2 0
+`)
+
+	buffer := &bytes.Buffer{}
+	assert.NoError(t, writeTreemap(buffer, buildTreemap(newReport(pkgs, coverageData, nil).Roots, newPrefixer("github.com/corsc/", nil)), 300, 200))
+
+	output := buffer.String()
+	assert.Contains(t, output, `<svg xmlns="http://www.w3.org/2000/svg" width="300" height="200"`)
+	assert.Contains(t, output, "<title>fu/\nbranch: 33.33% (1 of 3 statements)\nself: 100.00% (1 of 1 statements)\nchild: 0.00% (0 of 2 statements)</title>")
+	assert.Contains(t, output, ">fu/bar/</text>")
+	assert.Contains(t, output, `fill="hsl(0, 70%, 55%)"`)
+	assert.Contains(t, output, `fill="hsl(120, 70%, 55%)"`)
+	assert.Contains(t, output, "</svg>\n")
+}
+
+func TestWriteTreemap_WriteError(t *testing.T) {
+	pkgs, coverageData := getTestCoverage(t, `mode: set
+github.com/corsc/fu/a.go:3.24,4.12 1 1
+`)
+
+	err := writeTreemap(&failingWriter{}, buildTreemap(newReport(pkgs, coverageData, nil).Roots, newPrefixer("github.com/corsc/", nil)), 300, 200)
+	assert.Error(t, err)
+}
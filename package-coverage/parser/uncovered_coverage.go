@@ -0,0 +1,201 @@
+// Copyright 2017 Corey Scott http://www.sage42.org/
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	uncoveredLocationTemplate = "%s:%d:%d: %s not covered (%d %s)\n"
+	uncoveredSourceTemplate   = "  %s %5d | %s\n"
+)
+
+// uncoveredRange is a range of consecutive uncovered blocks in a file
+type uncoveredRange struct {
+	block
+
+	// location is the filename shown in the output (relative to the working directory when possible)
+	location string
+}
+
+// PrintUncovered will print the uncovered ranges of each package in the report (or only of the packages below the
+// minimum coverage) as "file:line:column: message" followed by the source with the supplied number of lines of context.
+// The locations can be used to jump to the uncovered code (e.g. from an editor's quickfix list).
+func PrintUncovered(writer io.Writer, report *Report, onlyBelow bool, minCoverage int, contextLines int, prefix string, depth int) {
+	selected := selectUncoveredPackages(report.Packages, onlyBelow, float64(minCoverage))
+	printUncovered(writer, getUncoveredRanges(report.getIncludedBlocks(), selected), newExcerptLoader(report.resolver.resolve), contextLines, newPrefixer(prefix, report.Modules), depth)
+}
+
+// returns the packages to list the uncovered code of (all packages or the packages below the minimum coverage)
+func selectUncoveredPackages(pkgs []*Package, onlyBelow bool, minCoverage float64) map[string]bool {
+	output := map[string]bool{}
+
+	for _, pkg := range pkgs {
+		if onlyBelow && pkg.Branch().Percent() >= minCoverage {
+			continue
+		}
+
+		output[pkg.Path] = true
+	}
+
+	return output
+}
+
+// returns the uncovered ranges of the selected packages sorted by location.
+// Uncovered blocks are combined when they are on the same or adjacent lines and there is no covered block between them.
+func getUncoveredRanges(blocks []block, selected map[string]bool) []*uncoveredRange {
+	blocksByFile := map[string][]block{}
+	for _, thisBlock := range blocks {
+		if selected[thisBlock.pkg] {
+			blocksByFile[thisBlock.filename()] = append(blocksByFile[thisBlock.filename()], thisBlock)
+		}
+	}
+
+	filenames := make([]string, 0, len(blocksByFile))
+	for filename := range blocksByFile {
+		filenames = append(filenames, filename)
+	}
+	sort.Strings(filenames)
+
+	var output []*uncoveredRange
+
+	for _, filename := range filenames {
+		fileBlocks := blocksByFile[filename]
+		sort.Slice(fileBlocks, func(i, j int) bool {
+			if fileBlocks[i].startLine != fileBlocks[j].startLine {
+				return fileBlocks[i].startLine < fileBlocks[j].startLine
+			}
+			return fileBlocks[i].startCol < fileBlocks[j].startCol
+		})
+
+		var current *uncoveredRange
+
+		for _, thisBlock := range fileBlocks {
+			if thisBlock.count > 0 {
+				current = nil
+				continue
+			}
+
+			if thisBlock.statements == 0 {
+				continue
+			}
+
+			if current != nil && thisBlock.startLine <= current.endLine+1 {
+				if thisBlock.endLine > current.endLine || (thisBlock.endLine == current.endLine && thisBlock.endCol > current.endCol) {
+					current.endLine, current.endCol = thisBlock.endLine, thisBlock.endCol
+				}
+				current.statements += thisBlock.statements
+				continue
+			}
+
+			current = &uncoveredRange{block: thisBlock}
+			output = append(output, current)
+		}
+	}
+
+	return output
+}
+
+func printUncovered(writer io.Writer, ranges []*uncoveredRange, excerpts *excerptLoader, contextLines int, prefix *prefixer, depth int) {
+	_, _ = fmt.Fprint(writer, "Uncovered code\n")
+	addLine(writer)
+
+	currentPkg := ""
+
+	for _, uncovered := range ranges {
+		pkgFormatted := prefix.trim(uncovered.pkg)
+		if !withinDepth(pkgFormatted, depth) {
+			continue
+		}
+
+		if uncovered.pkg != currentPkg {
+			_, _ = fmt.Fprintf(writer, "%s\n", pkgFormatted)
+			currentPkg = uncovered.pkg
+		}
+
+		_, _ = fmt.Fprintf(writer, uncoveredLocationTemplate, getUncoveredLocation(excerpts.resolve(uncovered.pkg, uncovered.file)),
+			uncovered.startLine, uncovered.startCol, getUncoveredLines(uncovered), uncovered.statements, pluralize("statement", uncovered.statements))
+
+		printUncoveredSource(writer, excerpts.lines(uncovered.block), uncovered, contextLines)
+	}
+	addLine(writer)
+}
+
+// print the uncovered lines (marked with >) and the lines of context around them
+func printUncoveredSource(writer io.Writer, lines []string, uncovered *uncoveredRange, contextLines int) {
+	if len(lines) == 0 {
+		return
+	}
+
+	first := uncovered.startLine - contextLines
+	if first < 1 {
+		first = 1
+	}
+
+	last := uncovered.endLine + contextLines
+	if last > len(lines) {
+		last = len(lines)
+	}
+
+	for number := first; number <= last; number++ {
+		marker := " "
+		if number >= uncovered.startLine && number <= uncovered.endLine {
+			marker = ">"
+		}
+
+		_, _ = fmt.Fprintf(writer, uncoveredSourceTemplate, marker, number, strings.TrimRight(lines[number-1], " \t\r"))
+	}
+}
+
+// returns the description of the lines of the range (e.g. "line 5" or "lines 5-7")
+func getUncoveredLines(uncovered *uncoveredRange) string {
+	if uncovered.startLine == uncovered.endLine {
+		return fmt.Sprintf("line %d", uncovered.startLine)
+	}
+	return fmt.Sprintf("lines %d-%d", uncovered.startLine, uncovered.endLine)
+}
+
+func pluralize(word string, count int) string {
+	if count == 1 {
+		return word
+	}
+	return word + "s"
+}
+
+// returns the filename relative to the working directory (when it is within it) so that the output is short and can
+// be used by editors
+func getUncoveredLocation(filename string) string {
+	if !filepath.IsAbs(filename) {
+		return filename
+	}
+
+	workingDir, err := os.Getwd()
+	if err != nil {
+		return filename
+	}
+
+	relative, err := filepath.Rel(workingDir, filename)
+	if err != nil || strings.HasPrefix(relative, "..") {
+		return filename
+	}
+
+	return relative
+}
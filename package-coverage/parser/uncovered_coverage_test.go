@@ -0,0 +1,107 @@
+// Copyright 2017 Corey Scott http://www.sage42.org/
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetUncoveredRanges(t *testing.T) {
+	in := `mode: set
+github.com/corsc/fu/a.go:3.24,4.12 1 1
+github.com/corsc/fu/a.go:4.12,6.3 1 0
+github.com/corsc/fu/a.go:7.2,7.14 1 0
+github.com/corsc/fu/a.go:12.33,14.2 1 0
+github.com/corsc/fu/b.go:1.1,2.2 2 0
+github.com/corsc/fu/b.go:3.1,3.5 1 1
+github.com/corsc/fu/b.go:4.1,4.5 1 0
+github.com/corsc/bar/c.go:1.1,2.2 2 0
+`
+	ranges := getUncoveredRanges(parseTestBlocks(t, in), map[string]bool{"github.com/corsc/fu/": true})
+
+	expected := []*uncoveredRange{
+		{block: block{pkg: "github.com/corsc/fu/", file: "a.go", startLine: 4, startCol: 12, endLine: 7, endCol: 14, statements: 2}},
+		{block: block{pkg: "github.com/corsc/fu/", file: "a.go", startLine: 12, startCol: 33, endLine: 14, endCol: 2, statements: 1}},
+		{block: block{pkg: "github.com/corsc/fu/", file: "b.go", startLine: 1, startCol: 1, endLine: 2, endCol: 2, statements: 2}},
+		{block: block{pkg: "github.com/corsc/fu/", file: "b.go", startLine: 4, startCol: 1, endLine: 4, endCol: 5, statements: 1}},
+	}
+	assert.Equal(t, expected, ranges)
+}
+
+func TestSelectUncoveredPackages(t *testing.T) {
+	coverageData := coverageByPackage{
+		"github.com/corsc/fu/":  {selfStatements: 10, selfCovered: 5},
+		"github.com/corsc/bar/": {selfStatements: 10, selfCovered: 9},
+	}
+	pkgs := newReport(getSortedPackages(coverageData), coverageData, nil).Packages
+
+	assert.Equal(t, map[string]bool{"github.com/corsc/fu/": true, "github.com/corsc/bar/": true}, selectUncoveredPackages(pkgs, false, 80))
+	assert.Equal(t, map[string]bool{"github.com/corsc/fu/": true}, selectUncoveredPackages(pkgs, true, 80))
+}
+
+func TestPrintUncovered(t *testing.T) {
+	dir, err := ioutil.TempDir("", "uncovered-coverage")
+	assert.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+
+	err = ioutil.WriteFile(filepath.Join(dir, "a.go"), []byte(sampleSourceFile), 0600)
+	assert.NoError(t, err)
+
+	in := `mode: set
+github.com/corsc/fu/a.go:3.24,4.12 1 1
+github.com/corsc/fu/a.go:4.12,6.3 1 0
+github.com/corsc/fu/a.go:7.2,7.14 1 1
+github.com/corsc/fu/b.go:1.1,1.5 1 0
+`
+	ranges := getUncoveredRanges(parseTestBlocks(t, in), map[string]bool{"github.com/corsc/fu/": true})
+	resolve := func(pkg, file string) string {
+		return filepath.Join(dir, file)
+	}
+
+	buffer := &bytes.Buffer{}
+	printUncovered(buffer, ranges, newExcerptLoader(resolve), 1, newPrefixer("github.com/corsc/", nil), 0)
+
+	expected := "fu/\n" +
+		filepath.Join(dir, "a.go") + ":4:12: lines 4-6 not covered (1 statement)\n" +
+		"        3 | func Add(a, b int) int {\n" +
+		"  >     4 | \tif a > 10 {\n" +
+		"  >     5 | \t\treturn 10\n" +
+		"  >     6 | \t}\n" +
+		"        7 | \treturn a + b\n" +
+		filepath.Join(dir, "b.go") + ":1:1: line 1 not covered (1 statement)\n"
+	assert.Contains(t, buffer.String(), expected)
+
+	// packages outside of the depth are not listed
+	buffer.Reset()
+	printUncovered(buffer, ranges, newExcerptLoader(resolve), 1, newPrefixer("github.com/", nil), 1)
+	assert.NotContains(t, buffer.String(), "a.go")
+}
+
+func TestGetUncoveredLocation(t *testing.T) {
+	workingDir, err := os.Getwd()
+	assert.NoError(t, err)
+
+	assert.Equal(t, "fu/a.go", getUncoveredLocation(filepath.Join(workingDir, "fu", "a.go")))
+	assert.Equal(t, "/not/within/a.go", getUncoveredLocation("/not/within/a.go"))
+	assert.Equal(t, "github.com/corsc/fu/a.go", getUncoveredLocation("github.com/corsc/fu/a.go"))
+}
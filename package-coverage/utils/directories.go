@@ -21,11 +21,15 @@ import (
 	"strings"
 )
 
+// TestResultsFilename is the file (in each directory) the go test -json event stream is saved to
+const TestResultsFilename = "profile.test.json"
+
 type mode int
 
 const (
 	goFiles mode = iota
 	coverageFiles
+	testResultFiles
 )
 
 // FindAllGoDirs will find all directories below the supplied with go files in them
@@ -38,23 +42,27 @@ func FindAllCoverageFiles(basePath string) ([]string, error) {
 	return finder(basePath, coverageFiles)
 }
 
+// FindAllTestResultFiles will find all the test results files below the supplied directory
+func FindAllTestResultFiles(basePath string) ([]string, error) {
+	return finder(basePath, testResultFiles)
+}
+
 func finder(basePath string, searchFor mode) ([]string, error) {
 	found := []string{}
 
-	oldCurrentDirectory := GetCurrentDir()
-	err := os.Chdir(basePath)
+	// walk the absolute path (rather than changing the working directory) so that this is safe to use concurrently
+	root, err := filepath.Abs(basePath)
 	if err != nil {
 		return nil, err
 	}
 
-	defer func() {
-		changeDirErr := os.Chdir(oldCurrentDirectory)
-		if err != nil {
-			LogWhenVerbose("failed to restore original directory with error %s", changeDirErr)
-		}
-	}()
+	// filepath.Walk does not follow a symlinked root
+	root, err = filepath.EvalSymlinks(root)
+	if err != nil {
+		return nil, err
+	}
 
-	_ = filepath.Walk("./", func(path string, finfo os.FileInfo, err error) error {
+	_ = filepath.Walk(root, func(path string, finfo os.FileInfo, err error) error {
 		if err != nil {
 			LogWhenVerbose("failed to check path '%s' with error %s", path, err)
 			return nil
@@ -64,10 +72,13 @@ func finder(basePath string, searchFor mode) ([]string, error) {
 
 		switch searchFor {
 		case goFiles:
-			foundPath, err = checkForGo(path, finfo)
+			foundPath, err = checkForGo(path, finfo, path == root)
 
 		case coverageFiles:
 			foundPath, err = checkForCoverage(path, finfo)
+
+		case testResultFiles:
+			foundPath, err = checkForTestResults(path, finfo)
 		}
 
 		if err != nil {
@@ -88,31 +99,30 @@ func finder(basePath string, searchFor mode) ([]string, error) {
 	return found, nil
 }
 
-func checkForGo(path string, finfo os.FileInfo) (string, error) {
+func checkForGo(path string, finfo os.FileInfo, root bool) (string, error) {
 	if !finfo.IsDir() {
 		return "", nil
 	}
 
-	_, filename := filepath.Split(path)
-	if strings.HasPrefix(filename, ".") || strings.HasPrefix(filename, "_") || filename == "testdata" {
-		return "", filepath.SkipDir
-	}
+	// the supplied directory is always searched (even when its name would otherwise be skipped)
+	if !root {
+		_, filename := filepath.Split(path)
+		if strings.HasPrefix(filename, ".") || strings.HasPrefix(filename, "_") || filename == "testdata" {
+			return "", filepath.SkipDir
+		}
 
-	pathEnd := getPathEnd(path)
+		pathEnd := getPathEnd(path)
 
-	if hiddenOrSystemDirs(pathEnd) {
-		return "", filepath.SkipDir
+		if hiddenOrSystemDirs(pathEnd) {
+			return "", filepath.SkipDir
+		}
 	}
 
 	if !hasGoFiles(path) {
 		return "", nil
 	}
 
-	if path == "./" {
-		return GetCurrentDir(), nil
-	}
-	foundPath := GetCurrentDir() + path + "/"
-	return foundPath, nil
+	return path + "/", nil
 }
 
 func checkForCoverage(path string, finfo os.FileInfo) (string, error) {
@@ -126,8 +136,19 @@ func checkForCoverage(path string, finfo os.FileInfo) (string, error) {
 	}
 
 	if strings.HasSuffix(path, ".cov") {
-		foundPath := GetCurrentDir() + path
-		return foundPath, nil
+		return path, nil
+	}
+	return "", nil
+}
+
+func checkForTestResults(path string, finfo os.FileInfo) (string, error) {
+	if finfo.IsDir() {
+		return "", nil
+	}
+
+	_, filename := filepath.Split(path)
+	if filename == TestResultsFilename {
+		return path, nil
 	}
 	return "", nil
 }
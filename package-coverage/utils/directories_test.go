@@ -28,7 +28,9 @@ func TestFindAllGoDirs(t *testing.T) {
 	expected := []string{
 		dir + "package-coverage/",
 		dir + "package-coverage/config/",
+		dir + "package-coverage/coverage/",
 		dir + "package-coverage/generator/",
+		dir + "package-coverage/notifier/",
 		dir + "package-coverage/parser/",
 		dir + "package-coverage/test-data/pathmatcher/",
 		dir + "package-coverage/test-data/pathmatcher/excluded/",
@@ -0,0 +1,252 @@
+// Copyright 2017 Corey Scott http://www.sage42.org/
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"bufio"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	goModFilename  = "go.mod"
+	goWorkFilename = "go.work"
+)
+
+// Module is a Go module (a directory containing a go.mod file)
+type Module struct {
+	// Dir is the absolute directory of the module (with a trailing slash)
+	Dir string
+
+	// Path is the module path from the go.mod file
+	Path string
+}
+
+// FindAllModules will find the module containing the supplied directory and all the modules below it
+// (sorted by directory)
+func FindAllModules(basePath string) ([]*Module, error) {
+	absBasePath, err := filepath.Abs(basePath)
+	if err != nil {
+		return nil, err
+	}
+
+	var output []*Module
+
+	if module := FindModule(absBasePath); module != nil {
+		output = append(output, module)
+	}
+
+	err = filepath.Walk(absBasePath, func(path string, finfo os.FileInfo, err error) error {
+		if err != nil {
+			LogWhenVerbose("failed to check path '%s' with error %s", path, err)
+			return nil
+		}
+
+		if !finfo.IsDir() {
+			return nil
+		}
+
+		if path != absBasePath {
+			name := finfo.Name()
+			if strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") || name == "testdata" || name == "vendor" {
+				return filepath.SkipDir
+			}
+		}
+
+		module := readModule(path)
+		if module != nil && (len(output) == 0 || output[0].Dir != module.Dir) {
+			output = append(output, module)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(output, func(i, j int) bool {
+		return output[i].Dir < output[j].Dir
+	})
+
+	return output, nil
+}
+
+// FindModule returns the module containing the supplied directory (or nil when it is not inside a module)
+func FindModule(dir string) *Module {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil
+	}
+
+	for {
+		if module := readModule(absDir); module != nil {
+			return module
+		}
+
+		parent := filepath.Dir(absDir)
+		if parent == absDir {
+			return nil
+		}
+
+		absDir = parent
+	}
+}
+
+// FindModuleForPackage returns the module the supplied package belongs to (the module with the longest matching path)
+func FindModuleForPackage(modules []*Module, pkg string) *Module {
+	var output *Module
+
+	for _, module := range modules {
+		if pkg != module.Path && !strings.HasPrefix(pkg, module.Path+"/") {
+			continue
+		}
+
+		if output == nil || len(module.Path) > len(output.Path) {
+			output = module
+		}
+	}
+
+	return output
+}
+
+// GetModulePrefix returns the prefix to remove from the package names of the supplied module (the module path without
+// its last element so that the module's root package keeps its name)
+func GetModulePrefix(module *Module) string {
+	lastSlash := strings.LastIndex(module.Path, "/")
+	if lastSlash == -1 {
+		return ""
+	}
+
+	return module.Path[:(lastSlash + 1)]
+}
+
+// returns the module in the supplied directory (or nil when there is no go.mod file)
+func readModule(dir string) *Module {
+	contents, err := ioutil.ReadFile(filepath.Join(dir, goModFilename))
+	if err != nil {
+		return nil
+	}
+
+	return &Module{
+		Dir:  dir + "/",
+		Path: getModulePath(string(contents)),
+	}
+}
+
+// extract the module path from the contents of a go.mod file
+func getModulePath(contents string) string {
+	scanner := bufio.NewScanner(strings.NewReader(contents))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "module" {
+			return strings.Trim(fields[1], `"`)
+		}
+	}
+
+	return ""
+}
+
+// FindWorkspace returns the go.work file used for the supplied directory and the (absolute) directories of the
+// modules it uses.  The work file is empty when there is no workspace (or workspaces are turned off with GOWORK=off).
+func FindWorkspace(dir string) (string, []string) {
+	workFile := os.Getenv("GOWORK")
+	if workFile == "off" {
+		return "", nil
+	}
+
+	if workFile == "" {
+		workFile = findWorkFile(dir)
+		if workFile == "" {
+			return "", nil
+		}
+	}
+
+	contents, err := ioutil.ReadFile(workFile)
+	if err != nil {
+		LogWhenVerbose("failed to read workspace '%s' with error %s", workFile, err)
+		return "", nil
+	}
+
+	var output []string
+	for _, use := range getWorkspaceUses(string(contents)) {
+		if !filepath.IsAbs(use) {
+			use = filepath.Join(filepath.Dir(workFile), use)
+		}
+
+		output = append(output, filepath.Clean(use)+"/")
+	}
+
+	return workFile, output
+}
+
+// find the go.work file in the supplied directory or its parents
+func findWorkFile(dir string) string {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+
+	for {
+		workFile := filepath.Join(absDir, goWorkFilename)
+		if _, err := os.Stat(workFile); err == nil {
+			return workFile
+		}
+
+		parent := filepath.Dir(absDir)
+		if parent == absDir {
+			return ""
+		}
+
+		absDir = parent
+	}
+}
+
+// extract the directories from the use directives (both "use dir" and "use ( ... )") of a go.work file
+func getWorkspaceUses(contents string) []string {
+	var output []string
+	inBlock := false
+
+	scanner := bufio.NewScanner(strings.NewReader(contents))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if comment := strings.Index(line, "//"); comment != -1 {
+			line = line[:comment]
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch {
+		case inBlock && fields[0] == ")":
+			inBlock = false
+
+		case inBlock:
+			output = append(output, strings.Trim(fields[0], `"`))
+
+		case fields[0] == "use" && len(fields) >= 2 && fields[1] == "(":
+			inBlock = true
+
+		case fields[0] == "use" && len(fields) >= 2:
+			output = append(output, strings.Trim(fields[1], `"`))
+		}
+	}
+
+	return output
+}
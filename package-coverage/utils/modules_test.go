@@ -0,0 +1,92 @@
+// Copyright 2017 Corey Scott http://www.sage42.org/
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindAllModules(t *testing.T) {
+	dir, err := ioutil.TempDir("", "modules")
+	assert.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+
+	for _, moduleDir := range []string{"", "nested", "vendor/skipped", "testdata/skipped"} {
+		assert.NoError(t, os.MkdirAll(filepath.Join(dir, moduleDir), 0700))
+		contents := "module " + path.Join("example.com/root", moduleDir) + "\n\ngo 1.20\n"
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, moduleDir, "go.mod"), []byte(contents), 0600))
+	}
+
+	modules, err := FindAllModules(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, []*Module{
+		{Dir: dir + "/", Path: "example.com/root"},
+		{Dir: dir + "/nested/", Path: "example.com/root/nested"},
+	}, modules)
+
+	// the enclosing module is found when starting below it
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "nested", "pkg"), 0700))
+	modules, err = FindAllModules(filepath.Join(dir, "nested", "pkg"))
+	assert.NoError(t, err)
+	assert.Equal(t, []*Module{{Dir: dir + "/nested/", Path: "example.com/root/nested"}}, modules)
+}
+
+func TestFindModule(t *testing.T) {
+	dir := strings.TrimSuffix(GetCurrentDir(), "utils/")
+
+	module := FindModule(GetCurrentDir())
+	assert.Equal(t, &Module{Dir: dir, Path: "github.com/corsc/go-tools/package-coverage"}, module)
+}
+
+func TestFindModuleForPackage(t *testing.T) {
+	root := &Module{Path: "github.com/corsc/go-tools"}
+	nested := &Module{Path: "github.com/corsc/go-tools/package-coverage"}
+	modules := []*Module{root, nested}
+
+	assert.Equal(t, nested, FindModuleForPackage(modules, "github.com/corsc/go-tools/package-coverage/parser"))
+	assert.Equal(t, nested, FindModuleForPackage(modules, "github.com/corsc/go-tools/package-coverage"))
+	assert.Equal(t, root, FindModuleForPackage(modules, "github.com/corsc/go-tools/package-coverage-other"))
+	assert.Nil(t, FindModuleForPackage(modules, "github.com/corsc/other"))
+}
+
+func TestGetModulePrefix(t *testing.T) {
+	assert.Equal(t, "github.com/corsc/go-tools/", GetModulePrefix(&Module{Path: "github.com/corsc/go-tools/package-coverage"}))
+	assert.Equal(t, "", GetModulePrefix(&Module{Path: "fu"}))
+}
+
+func TestGetWorkspaceUses(t *testing.T) {
+	contents := `go 1.20
+
+use ./one // the first one
+
+use (
+	./two
+	"../three"
+	// ./commented
+)
+
+replace example.com/fu => ./fu
+`
+	assert.Equal(t, []string{"./one", "./two", "../three"}, getWorkspaceUses(contents))
+}
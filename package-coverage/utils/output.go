@@ -0,0 +1,170 @@
+// Copyright 2017 Corey Scott http://www.sage42.org/
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// workDirName is the directory (in the user's cache directory) the work directory of each base path is kept in
+const workDirName = "package-coverage"
+
+// outputDirName is the directory (in the work directory) the coverage and test results are written to
+const outputDirName = "output"
+
+// GetWorkDir returns the directory (with a trailing slash) the output, journal and overlay of the supplied base path
+// are kept in.  The directory is derived from the absolute base path so that every step of a run (and a later clean up
+// or recovery) uses the same directory.  It is kept in the user's cache directory (or a per-user directory in the
+// temporary directory when there is none) and created with 0700 permissions; directories that are not owned by the
+// current user or that other users can write to are refused.
+func GetWorkDir(basePath string) (string, error) {
+	root, err := getRootDir()
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.Sum256([]byte(getRealPath(basePath)))
+	dir := filepath.Join(root, hex.EncodeToString(hash[:8]))
+
+	err = createPrivateDir(dir)
+	if err != nil {
+		return "", err
+	}
+
+	return dir + "/", nil
+}
+
+// GetOutputDir returns the directory (with a trailing slash) the coverage and test results of the supplied base path
+// are written to (see GetWorkDir)
+func GetOutputDir(basePath string) (string, error) {
+	workDir, err := GetWorkDir(basePath)
+	if err != nil {
+		return "", err
+	}
+
+	return workDir + outputDirName + "/", nil
+}
+
+// GetOutputPath returns the directory (with a trailing slash) in the output directory the coverage and test results of
+// the supplied directory are written to (the directory relative to the base path)
+func GetOutputPath(outputDir string, basePath string, dir string) string {
+	relPath, err := filepath.Rel(getRealPath(basePath), getRealPath(dir))
+	if err != nil || strings.HasPrefix(relPath, "..") {
+		LogAlways("directory '%s' is not below '%s'; using the output directory of the base path", dir, basePath)
+		relPath = "."
+	}
+
+	return strings.TrimSuffix(filepath.Join(outputDir, relPath), "/") + "/"
+}
+
+// GetSourcePath returns the directory (with a trailing slash) the supplied directory in the output directory contains
+// the coverage and test results of (the reverse of GetOutputPath)
+func GetSourcePath(outputDir string, basePath string, outputPath string) string {
+	relPath, err := filepath.Rel(outputDir, getRealPath(outputPath))
+	if err != nil || strings.HasPrefix(relPath, "..") {
+		return strings.TrimSuffix(outputPath, "/") + "/"
+	}
+
+	return strings.TrimSuffix(filepath.Join(getRealPath(basePath), relPath), "/") + "/"
+}
+
+// IsWithin returns true when the supplied path (with any symlinks in its parent directories resolved) is inside the
+// supplied directory (and is not the directory itself)
+func IsWithin(dir string, path string) bool {
+	dir = filepath.Clean(dir)
+
+	parent := filepath.Dir(filepath.Clean(path))
+	if realParent, err := filepath.EvalSymlinks(parent); err == nil {
+		parent = realParent
+	}
+
+	return parent == dir || strings.HasPrefix(parent, dir+string(filepath.Separator))
+}
+
+// CheckPrivate returns an error when the supplied file is a symlink, is not owned by the current user or can be
+// written by other users
+func CheckPrivate(name string, info os.FileInfo) error {
+	if info.Mode()&os.ModeSymlink != 0 {
+		return fmt.Errorf("'%s' is a symlink", name)
+	}
+
+	if info.Mode().Perm()&0022 != 0 {
+		return fmt.Errorf("'%s' can be written by other users", name)
+	}
+
+	if !isOwner(info) {
+		return fmt.Errorf("'%s' is not owned by the current user", name)
+	}
+
+	return nil
+}
+
+// returns the per-user directory the work directories are kept in (creating it when missing)
+func getRootDir() (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		// e.g. $HOME is not set; the directory is named after the user so that each user has their own
+		dir := filepath.Join(getRealPath(os.TempDir()), fmt.Sprintf("%s-%d", workDirName, os.Getuid()))
+		return dir, createPrivateDir(dir)
+	}
+
+	err = os.MkdirAll(cacheDir, 0700)
+	if err != nil {
+		return "", err
+	}
+
+	dir := filepath.Join(getRealPath(cacheDir), workDirName)
+	return dir, createPrivateDir(dir)
+}
+
+// create the directory (when missing) with 0700 permissions and check that it is private
+func createPrivateDir(dir string) error {
+	err := os.Mkdir(dir, 0700)
+	if err != nil && !os.IsExist(err) {
+		return err
+	}
+
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return err
+	}
+
+	if !info.IsDir() && info.Mode()&os.ModeSymlink == 0 {
+		return fmt.Errorf("'%s' is not a directory", dir)
+	}
+
+	return CheckPrivate(dir, info)
+}
+
+// returns the absolute path with any symlinks resolved (when possible) so that the same directory always results in
+// the same path
+func getRealPath(path string) string {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+
+	realPath, err := filepath.EvalSymlinks(absPath)
+	if err != nil {
+		return absPath
+	}
+
+	return realPath
+}
@@ -0,0 +1,96 @@
+// Copyright 2017 Corey Scott http://www.sage42.org/
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetOutputDir(t *testing.T) {
+	outputDir, err := GetOutputDir("./")
+	assert.NoError(t, err)
+	defer removeTestWorkDir("./")
+
+	// the output is not written to the source tree and does not depend on how the base path is supplied
+	assert.False(t, strings.HasPrefix(outputDir, GetCurrentDir()), outputDir)
+
+	otherOutputDir, err := GetOutputDir(GetCurrentDir())
+	assert.NoError(t, err)
+	assert.Equal(t, outputDir, otherOutputDir)
+
+	otherOutputDir, err = GetOutputDir("../")
+	assert.NoError(t, err)
+	defer removeTestWorkDir("../")
+	assert.NotEqual(t, outputDir, otherOutputDir)
+
+	// the work directory is private
+	workDir, err := GetWorkDir("./")
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(outputDir, workDir), outputDir)
+
+	info, err := os.Lstat(workDir)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0700), info.Mode().Perm())
+}
+
+func TestGetWorkDir_NotPrivate(t *testing.T) {
+	workDir, err := GetWorkDir("./")
+	assert.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(workDir)
+	}()
+
+	// work directories other users can write to are refused
+	assert.NoError(t, os.Chmod(workDir, 0777))
+
+	_, err = GetWorkDir("./")
+	assert.Error(t, err)
+}
+
+func TestGetOutputPath(t *testing.T) {
+	outputDir := "/tmp/output/"
+	basePath := filepath.Join(GetCurrentDir(), "..")
+	dir := filepath.Join(basePath, "parser") + "/"
+
+	outputPath := GetOutputPath(outputDir, basePath, dir)
+	assert.Equal(t, outputDir+"parser/", outputPath)
+	assert.Equal(t, outputDir, GetOutputPath(outputDir, basePath, basePath))
+
+	// the output path can be reversed to find the source
+	assert.Equal(t, dir, GetSourcePath(outputDir, basePath, outputPath))
+	assert.Equal(t, getRealPath(basePath)+"/", GetSourcePath(outputDir, basePath, outputDir))
+}
+
+func TestIsWithin(t *testing.T) {
+	assert.True(t, IsWithin("/fu", "/fu/bar"))
+	assert.True(t, IsWithin("/fu", "/fu/bar/baz.cov"))
+	assert.False(t, IsWithin("/fu", "/fu"))
+	assert.False(t, IsWithin("/fu", "/fubar/baz.cov"))
+	assert.False(t, IsWithin("/fu", "/fu/../bar"))
+	assert.False(t, IsWithin("/fu", "/bar"))
+}
+
+// remove the work directory created by a test
+func removeTestWorkDir(basePath string) {
+	workDir, err := GetWorkDir(basePath)
+	if err == nil {
+		_ = os.RemoveAll(workDir)
+	}
+}